@@ -0,0 +1,107 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SqliteCloneSpec defines the desired state of SqliteClone.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable, create a new SqliteClone instead"
+type SqliteCloneSpec struct {
+	// Database whose Litestream replica is restored
+	Source CloneSource `json:"source"`
+
+	// Point in time to restore, the latest replicated state when unset
+	Timestamp *metav1.Time `json:"timestamp,omitempty"`
+
+	// Database created from the replica
+	Target CloneTarget `json:"target,omitempty"`
+}
+
+// CloneSource defines the database to clone
+type CloneSource struct {
+	// Name of the source SqliteDatabase
+	Name string `json:"name"`
+
+	// Namespace of the source SqliteDatabase, the namespace of the clone when unset
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// CloneTarget defines the database created by the clone
+type CloneTarget struct {
+	// Name of the new SqliteDatabase, the name of the clone when unset
+	Name string `json:"name,omitempty"`
+
+	// Namespace of the new SqliteDatabase, the namespace of the clone when unset
+	Namespace string `json:"namespace,omitempty"`
+
+	// Replication of the new database. It is disabled when unset, so that the clone never
+	// writes to the replica of the source database.
+	Litestream *LitestreamConfig `json:"litestream,omitempty"`
+
+	// Rules scrubbing the restored data before the database is first served
+	Masking []MaskingRule `json:"masking,omitempty"`
+}
+
+// SqliteCloneStatus defines the observed state of SqliteClone.
+type SqliteCloneStatus struct {
+	// Current phase of the clone
+	Phase string `json:"phase,omitempty"`
+
+	// Human-readable message about the current state
+	Message string `json:"message,omitempty"`
+
+	// Namespaced name of the created SqliteDatabase
+	Database string `json:"database,omitempty"`
+
+	// Conditions represent the latest available observations
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source.name`
+// +kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.status.database`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SqliteClone is the Schema for the sqliteclones API. It creates a new SqliteDatabase whose
+// data is restored from the Litestream replica of another database.
+type SqliteClone struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SqliteCloneSpec   `json:"spec,omitempty"`
+	Status SqliteCloneStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SqliteCloneList contains a list of SqliteClone.
+type SqliteCloneList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SqliteClone `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SqliteClone{}, &SqliteCloneList{})
+}
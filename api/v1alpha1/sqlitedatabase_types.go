@@ -22,6 +22,19 @@ import (
 )
 
 // SqliteDatabaseSpec defines the desired state of SqliteDatabase.
+// +kubebuilder:validation:XValidation:rule="!has(self.database) || !has(self.database.storage.type) || self.database.storage.type != 'ephemeral' || (has(self.litestream) && self.litestream.enabled && has(self.litestream.replicas) && size(self.litestream.replicas) > 0)",message="ephemeral storage requires Litestream with at least one replica"
+// +kubebuilder:validation:XValidation:rule="!has(self.database) || !has(self.database.masking) || ((!has(self.database.storage.type) || self.database.storage.type != 'ephemeral') && (!has(self.workload) || !has(self.workload.kind) || self.workload.kind != 'StatefulSet'))",message="masking cannot be used with ephemeral storage or the StatefulSet workload"
+// +kubebuilder:validation:XValidation:rule="!has(self.database) || !has(self.database.storage.existingClaim) || ((!has(self.database.storage.type) || self.database.storage.type != 'ephemeral') && (!has(self.workload) || !has(self.workload.kind) || self.workload.kind != 'StatefulSet'))",message="existingClaim cannot be used with ephemeral storage or the StatefulSet workload"
+// +kubebuilder:validation:XValidation:rule="!has(self.readReplicas) || (has(self.litestream) && self.litestream.enabled && has(self.litestream.replicas) && size(self.litestream.replicas) > 0 && has(self.sqliteRest) && self.sqliteRest.enabled)",message="readReplicas require Litestream with at least one replica and sqliteRest"
+// +kubebuilder:validation:XValidation:rule="!has(self.replication) || self.replication.mode != 'litefs' || ((!has(self.litestream) || !self.litestream.enabled) && !has(self.readReplicas) && !has(self.writerLease) && !has(self.workload) && !has(self.database.initScript) && !has(self.database.dataSource) && !has(self.database.encryption) && !has(self.database.storage.existingClaim) && (!has(self.database.storage.type) || self.database.storage.type != 'ephemeral'))",message="replication mode litefs cannot be used with litestream, readReplicas, writerLease, workload, initScript, dataSource, encryption, existingClaim or ephemeral storage"
+// +kubebuilder:validation:XValidation:rule="(has(self.replication) && self.replication.mode == 'litefs') == (has(oldSelf.replication) && oldSelf.replication.mode == 'litefs')",message="replication mode litefs cannot be enabled or disabled on an existing database"
+// +kubebuilder:validation:XValidation:rule="!has(self.standby) || (!has(self.database.initScript) && !has(self.database.dataSource) && (!has(self.workload) || !has(self.workload.kind) || self.workload.kind != 'None') && (!has(self.replication) || self.replication.mode != 'litefs'))",message="standby cannot be used with initScript, dataSource, the None workload or LiteFS"
+// +kubebuilder:validation:XValidation:rule="!has(self.standby) || self.standby.promote || !has(self.readReplicas)",message="readReplicas require the standby to be promoted"
+// +kubebuilder:validation:XValidation:rule="!has(self.recovery) || !self.recovery.autoRestore || (has(self.litestream) && self.litestream.enabled && has(self.litestream.replicas) && size(self.litestream.replicas) > 0 && !has(self.standby))",message="recovery.autoRestore requires Litestream with at least one replica and cannot be used with standby"
+// +kubebuilder:validation:XValidation:rule="!has(self.agent) || !self.agent.enabled || ((!has(self.workload) || !has(self.workload.kind) || self.workload.kind != 'None') && (!has(self.replication) || self.replication.mode != 'litefs'))",message="agent cannot be used with the None workload or LiteFS"
+// +kubebuilder:validation:XValidation:rule="!has(self.maintenance) || ((!has(self.workload) || !has(self.workload.kind) || self.workload.kind != 'None') && (!has(self.replication) || self.replication.mode != 'litefs'))",message="maintenance cannot be used with the None workload or LiteFS"
+// +kubebuilder:validation:XValidation:rule="!has(self.database) || !has(self.database.encryption) || (has(self.litestream) && !self.litestream.enabled && (!has(self.sqliteRest) || !self.sqliteRest.enabled))",message="encryption requires litestream.enabled: false and sqliteRest disabled, Litestream and sqlite-rest cannot open SQLCipher databases"
+// +kubebuilder:validation:XValidation:rule="!has(self.litestream) || !has(self.litestream.mode) || self.litestream.mode != 'exec' || (has(self.workload) && has(self.workload.kind) && self.workload.kind == 'None')",message="litestream mode exec requires the None workload"
 type SqliteDatabaseSpec struct {
 	// Database configuration
 	Database DatabaseConfig `json:"database,omitempty"`
@@ -37,9 +50,52 @@ type SqliteDatabaseSpec struct {
 
 	// Resource requirements for the pod
 	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Container image overrides for this database
+	Images *ImagesConfig `json:"images,omitempty"`
+
+	// Image pull policy for all containers
+	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// Secrets used to pull the container images
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// Overrides merged onto the generated database pod template
+	PodTemplate *PodTemplateConfig `json:"podTemplate,omitempty"`
+
+	// Writer lease ensuring at most one pod writes to the database at any time. Disabled
+	// unless set, requires native sidecar containers (Kubernetes 1.29+).
+	WriterLease *WriterLeaseConfig `json:"writerLease,omitempty"`
+
+	// Workload running the database pod
+	Workload *WorkloadConfig `json:"workload,omitempty"`
+
+	// Read-only pods following the Litestream replica and serving sqlite-rest
+	ReadReplicas *ReadReplicasConfig `json:"readReplicas,omitempty"`
+
+	// Replication mode of the database, Litestream by default
+	Replication *ReplicationConfig `json:"replication,omitempty"`
+
+	// Follow the replica of a database in another cluster until promoted
+	Standby *StandbyConfig `json:"standby,omitempty"`
+
+	// Scheduled integrity checks, VACUUM, ANALYZE and WAL checkpoints
+	Maintenance *MaintenanceConfig `json:"maintenance,omitempty"`
+
+	// Recovery of a corrupt database from the Litestream replica
+	Recovery *RecoveryConfig `json:"recovery,omitempty"`
+
+	// In-pod agent reporting the statistics of the database and running control commands
+	Agent *AgentConfig `json:"agent,omitempty"`
+
+	// Refresh of the statistics of the database published in the status
+	Statistics *StatisticsConfig `json:"statistics,omitempty"`
 }
 
 // DatabaseConfig defines SQLite database configuration
+// +kubebuilder:validation:XValidation:rule="!has(self.initScript) || !has(self.dataSource)",message="initScript and dataSource are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.masking) || has(self.dataSource)",message="masking requires a dataSource"
 type DatabaseConfig struct {
 	// Name of the SQLite database file
 	// +kubebuilder:default="database.db"
@@ -48,11 +104,118 @@ type DatabaseConfig struct {
 	// Name of ConfigMap containing SQL initialization script
 	InitScript *string `json:"initScript,omitempty"`
 
+	// File imported into the database when it is first created, instead of the init script
+	DataSource *DataSourceConfig `json:"dataSource,omitempty"`
+
+	// Rules scrubbing the data source in a Job before the database is first served
+	Masking []MaskingRule `json:"masking,omitempty"`
+
 	// Storage configuration for the database
 	Storage StorageConfig `json:"storage"`
+
+	// Encryption at rest for the database file using SQLCipher
+	Encryption *EncryptionConfig `json:"encryption,omitempty"`
+}
+
+// DataSourceConfig defines a .sql dump or .db file imported into a new database. The file is
+// only imported when the database does not exist yet, after any restore from the replica.
+// +kubebuilder:validation:XValidation:rule="[has(self.configMap), has(self.secret), has(self.persistentVolumeClaim), has(self.url), has(self.replica)].filter(x, x).size() == 1",message="exactly one of configMap, secret, persistentVolumeClaim, url or replica must be set"
+type DataSourceConfig struct {
+	// Key of a ConfigMap holding the file
+	ConfigMap *corev1.ConfigMapKeySelector `json:"configMap,omitempty"`
+
+	// Key of a Secret holding the file
+	Secret *corev1.SecretKeySelector `json:"secret,omitempty"`
+
+	// File on another PersistentVolumeClaim
+	PersistentVolumeClaim *ClaimDataSource `json:"persistentVolumeClaim,omitempty"`
+
+	// HTTP(S) or S3 object holding the file
+	URL *URLDataSource `json:"url,omitempty"`
+
+	// Litestream replica of another database, restored as a database file
+	Replica *ReplicaDataSource `json:"replica,omitempty"`
+
+	// Format of the file, detected from its name when unset: .sql files are dumps, other files databases
+	// +kubebuilder:validation:Enum=sql;db
+	Format string `json:"format,omitempty"`
+}
+
+// ClaimDataSource defines a file on a PersistentVolumeClaim
+type ClaimDataSource struct {
+	// Name of the PersistentVolumeClaim, mounted read-only
+	ClaimName string `json:"claimName"`
+
+	// Path of the file within the volume
+	// +kubebuilder:validation:Pattern="^[^/]"
+	// +kubebuilder:validation:XValidation:rule="!self.contains('..')",message="path must not contain '..'"
+	Path string `json:"path"`
+}
+
+// URLDataSource defines a file downloaded over HTTP(S) or from S3
+type URLDataSource struct {
+	// URL of the file, http://, https:// or s3://bucket/key
+	// +kubebuilder:validation:Pattern="^(https?|s3)://"
+	URL string `json:"url"`
+
+	// SHA-256 checksum of the file, verified before it is imported
+	// +kubebuilder:validation:Pattern="^[a-fA-F0-9]{64}$"
+	SHA256 string `json:"sha256"`
+
+	// Region of the S3 bucket
+	Region *string `json:"region,omitempty"`
+
+	// Custom S3 endpoint
+	Endpoint *string `json:"endpoint,omitempty"`
+
+	// Credentials for S3, anonymous when unset
+	Credentials *CredentialsConfig `json:"credentials,omitempty"`
+}
+
+// ReplicaDataSource defines the Litestream replica of another database
+type ReplicaDataSource struct {
+	// Replicas of the source database, as in its litestream.replicas
+	// +kubebuilder:validation:MinItems=1
+	Replicas []ReplicaConfig `json:"replicas"`
+
+	// Name of the source database file
+	Database string `json:"database"`
+
+	// Point in time to restore, the latest replicated state when unset
+	Timestamp *metav1.Time `json:"timestamp,omitempty"`
+}
+
+// MaskingRule defines how a column or table of the data source is scrubbed
+// +kubebuilder:validation:XValidation:rule="self.strategy == 'drop' || has(self.column)",message="column is required unless the strategy is drop"
+// +kubebuilder:validation:XValidation:rule="self.strategy != 'fixed' || has(self.value)",message="value is required with the fixed strategy"
+type MaskingRule struct {
+	// Table to scrub
+	Table string `json:"table"`
+
+	// Column to scrub, unused by the drop strategy
+	Column string `json:"column,omitempty"`
+
+	// Strategy replacing the values: null, hash (SHA3-256 in hex), fakeEmail (a fake address
+	// derived from the hash of the value), fixed (value) or drop (the whole table)
+	// +kubebuilder:validation:Enum=null;hash;fakeEmail;fixed;drop
+	Strategy string `json:"strategy"`
+
+	// Value set by the fixed strategy
+	Value *string `json:"value,omitempty"`
+}
+
+// EncryptionConfig defines SQLCipher encryption for the database file
+type EncryptionConfig struct {
+	// Name of the Secret containing the encryption key
+	SecretName string `json:"secretName"`
+
+	// Field name for the encryption key in the secret
+	// +kubebuilder:default="key"
+	KeyField *string `json:"keyField,omitempty"`
 }
 
 // StorageConfig defines storage configuration for the database
+// +kubebuilder:validation:XValidation:rule="!has(self.allowEmpty) || !self.allowEmpty || (has(self.type) && self.type == 'ephemeral')",message="allowEmpty can only be set with ephemeral storage"
 type StorageConfig struct {
 	// Size of the persistent volume
 	// +kubebuilder:default="1Gi"
@@ -66,9 +229,58 @@ type StorageConfig struct {
 	// +kubebuilder:default="ReadWriteMany"
 	// +kubebuilder:validation:Enum=ReadWriteOnce;ReadWriteMany;ReadOnlyMany
 	AccessMode string `json:"accessMode,omitempty"`
+
+	// Type of storage. Ephemeral keeps the database in an emptyDir limited to size, restored
+	// from the Litestream replica on every start, so durability depends entirely on replication.
+	// +kubebuilder:default="persistent"
+	// +kubebuilder:validation:Enum=persistent;ephemeral
+	Type string `json:"type,omitempty"`
+
+	// Medium of the ephemeral emptyDir, Memory for a tmpfs counted against the pod memory
+	// +kubebuilder:validation:Enum="";Memory
+	Medium corev1.StorageMedium `json:"medium,omitempty"`
+
+	// Start an ephemeral database empty when its replica does not exist, as on its first start.
+	// Otherwise the pod does not start until the replica exists, so that a replica that cannot
+	// be found is never replaced by an empty database.
+	AllowEmpty bool `json:"allowEmpty,omitempty"`
+
+	// Automatic expansion of the volume as the database grows
+	AutoGrow *AutoGrowConfig `json:"autoGrow,omitempty"`
+
+	// Existing PersistentVolumeClaim holding the database, used instead of <name>-db-storage.
+	// The claim is referenced but not owned, so it is kept when the SqliteDatabase is deleted.
+	ExistingClaim string `json:"existingClaim,omitempty"`
+
+	// Directory within the volume mounted at /var/lib/sqlite
+	// +kubebuilder:validation:Pattern="^[^/]"
+	// +kubebuilder:validation:XValidation:rule="!self.contains('..')",message="subPath must not contain '..'"
+	SubPath string `json:"subPath,omitempty"`
+}
+
+// AutoGrowConfig defines the automatic expansion of the database volume
+type AutoGrowConfig struct {
+	// Enable automatic expansion
+	Enabled bool `json:"enabled"`
+
+	// Percentage of the volume used by the database and its WAL that triggers an expansion
+	// +kubebuilder:default=80
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=99
+	ThresholdPercent int32 `json:"thresholdPercent,omitempty"`
+
+	// Size added to the volume on each expansion
+	// +kubebuilder:default="1Gi"
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(E|P|T|G|M|K|Ei|Pi|Ti|Gi|Mi|Ki)?)$"
+	Increment string `json:"increment,omitempty"`
+
+	// Maximum size of the volume
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(E|P|T|G|M|K|Ei|Pi|Ti|Gi|Mi|Ki)?)$"
+	MaxSize string `json:"maxSize"`
 }
 
 // LitestreamConfig defines Litestream replication configuration
+// +kubebuilder:validation:XValidation:rule="!has(self.verification) || (has(self.replicas) && self.verification.replica < size(self.replicas))",message="verification.replica must be the index of a replica"
 type LitestreamConfig struct {
 	// Enable Litestream replication
 	// +kubebuilder:default=true
@@ -76,6 +288,58 @@ type LitestreamConfig struct {
 
 	// List of replication targets
 	Replicas []ReplicaConfig `json:"replicas,omitempty"`
+
+	// Mode of replication. In exec mode the application container of the pods annotated with
+	// sqlite.io/database runs as a child process of Litestream instead of next to a sidecar.
+	// +kubebuilder:default="sidecar"
+	// +kubebuilder:validation:Enum=sidecar;exec
+	Mode string `json:"mode,omitempty"`
+
+	// Exec configures the exec mode
+	Exec *LitestreamExecConfig `json:"exec,omitempty"`
+
+	// Verify a replica periodically by restoring it
+	Verification *BackupVerificationConfig `json:"verification,omitempty"`
+}
+
+// BackupVerificationConfig defines a CronJob restoring the latest state of a replica into
+// scratch storage and checking it, reported by the BackupVerified condition
+type BackupVerificationConfig struct {
+	// Schedule of the verification in cron format
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Index in replicas of the replica to verify
+	// +kubebuilder:default=0
+	// +kubebuilder:validation:Minimum=0
+	Replica int32 `json:"replica,omitempty"`
+
+	// SQL assertions checked on the restored database after PRAGMA integrity_check
+	Assertions []BackupAssertion `json:"assertions,omitempty"`
+
+	// Resource requirements of the verification Job
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// BackupAssertion defines a query that must return 1 on the restored database, such as
+// SELECT count(*) > 0 FROM users
+type BackupAssertion struct {
+	// Name of the assertion, reported when it fails
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Query returning a single value, 1 when the assertion holds
+	// +kubebuilder:validation:MinLength=1
+	Query string `json:"query"`
+}
+
+// LitestreamExecConfig defines the application run by Litestream in exec mode
+type LitestreamExecConfig struct {
+	// Container to wrap, defaults to the first container of the pod
+	Container string `json:"container,omitempty"`
+
+	// Command of the application, defaults to the command and args of the container
+	Command []string `json:"command,omitempty"`
 }
 
 // ReplicaConfig defines individual replica configuration
@@ -122,6 +386,277 @@ type CredentialsConfig struct {
 	SecretKeyField *string `json:"secretKeyField,omitempty"`
 }
 
+// ImagesConfig defines per-database container image overrides
+type ImagesConfig struct {
+	// Image providing the sqlite3 (or sqlcipher) CLI for the init container
+	Sqlite *string `json:"sqlite,omitempty"`
+
+	// Litestream image
+	Litestream *string `json:"litestream,omitempty"`
+
+	// sqlite-rest image
+	SqliteRest *string `json:"sqliteRest,omitempty"`
+
+	// LiteFS image
+	LiteFS *string `json:"litefs,omitempty"`
+}
+
+// PodTemplateConfig defines scheduling, security and metadata overrides for the database pod
+type PodTemplateConfig struct {
+	// Additional labels for the pod
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Additional annotations for the pod
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Node selector for scheduling the pod
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations for the pod
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity scheduling rules for the pod
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Topology spread constraints for the pod
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// Priority class name for the pod
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Pod-level security context, e.g. fsGroup so the database file is writable by the application UID
+	SecurityContext *corev1.PodSecurityContext `json:"securityContext,omitempty"`
+
+	// Security context applied to every container in the pod
+	ContainerSecurityContext *corev1.SecurityContext `json:"containerSecurityContext,omitempty"`
+
+	// Grace period for the pod to terminate
+	// +kubebuilder:validation:Minimum=0
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+}
+
+// WriterLeaseConfig defines the coordination.k8s.io Lease held by the writer pod
+// +kubebuilder:validation:XValidation:rule="self.renewDeadlineSeconds < self.leaseDurationSeconds",message="renewDeadlineSeconds must be less than leaseDurationSeconds"
+type WriterLeaseConfig struct {
+	// Enable the writer lease
+	// +kubebuilder:default=true
+	Enabled bool `json:"enabled"`
+
+	// Seconds a new pod waits before taking over a lease that is no longer renewed
+	// +kubebuilder:default=15
+	// +kubebuilder:validation:Minimum=2
+	LeaseDurationSeconds int32 `json:"leaseDurationSeconds,omitempty"`
+
+	// Seconds the holder retries renewing the lease before its writers are stopped.
+	// Must be less than leaseDurationSeconds.
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=1
+	RenewDeadlineSeconds int32 `json:"renewDeadlineSeconds,omitempty"`
+}
+
+// WorkloadConfig defines the workload running the database pod
+type WorkloadConfig struct {
+	// Kind of workload. A StatefulSet uses a volumeClaimTemplate (db-storage-<name>-0), a stable
+	// pod identity and a headless Service. Switching kinds re-binds the existing volume.
+	// None runs no workload, the database then lives in the application pods annotated
+	// with sqlite.io/database: <name>.
+	// +kubebuilder:default="Deployment"
+	// +kubebuilder:validation:Enum=Deployment;StatefulSet;None
+	Kind string `json:"kind,omitempty"`
+}
+
+// ReadReplicasConfig defines read-only pods restoring the Litestream replica of the database
+// into local storage and serving it with sqlite-rest behind the <name>-read Service
+type ReadReplicasConfig struct {
+	// Number of read replica pods
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	Count int32 `json:"count"`
+
+	// Resource requirements of the sqlite-rest container of the read replicas
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Seconds between checks of the replica for new WAL segments
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=1
+	SyncIntervalSeconds int32 `json:"syncIntervalSeconds,omitempty"`
+}
+
+// ReplicationConfig defines how the database is replicated
+type ReplicationConfig struct {
+	// Mode of replication. litestream streams the WAL of the single database pod to the
+	// replicas of spec.litestream. litefs runs the database on a StatefulSet of LiteFS pods,
+	// one primary accepting writes behind the <name>-primary Service and the others
+	// replicating it, and serving reads behind the <name>-service Service.
+	// +kubebuilder:default="litestream"
+	// +kubebuilder:validation:Enum=litestream;litefs
+	Mode string `json:"mode,omitempty"`
+
+	// LiteFS configuration, used with the litefs mode
+	// +kubebuilder:default={}
+	LiteFS *LiteFSConfig `json:"litefs,omitempty"`
+}
+
+// LiteFSConfig defines the LiteFS pods and the election of their primary
+// +kubebuilder:validation:XValidation:rule="self.renewDeadlineSeconds < self.leaseDurationSeconds",message="renewDeadlineSeconds must be less than leaseDurationSeconds"
+type LiteFSConfig struct {
+	// Number of LiteFS pods, including the primary
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=1
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Election of the primary. kubernetes elects the holder of the <name>-litefs Lease, so
+	// that another pod takes over when the primary fails, after restarting to mount LiteFS
+	// as the primary. static always makes pod 0 the primary, the others only replicate it.
+	// +kubebuilder:default="kubernetes"
+	// +kubebuilder:validation:Enum=kubernetes;static
+	Lease string `json:"lease,omitempty"`
+
+	// Seconds the other pods wait after the last renewal of the Lease before electing a new
+	// primary. A primary shutting down releases the Lease at once. Writes resume once the
+	// elected pod restarted, so failover takes this duration plus a pod restart.
+	// +kubebuilder:default=5
+	// +kubebuilder:validation:Minimum=2
+	LeaseDurationSeconds int32 `json:"leaseDurationSeconds,omitempty"`
+
+	// Seconds the primary retries renewing the Lease before stepping down
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=1
+	RenewDeadlineSeconds int32 `json:"renewDeadlineSeconds,omitempty"`
+
+	// Resource requirements of the litefs container
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// StandbyConfig defines a standby database continuously restoring the replica of its source,
+// typically the same database in another cluster, without writing to it
+// +kubebuilder:validation:XValidation:rule="!has(oldSelf.promote) || !oldSelf.promote || self.promote",message="a promoted standby cannot follow its source again"
+type StandbyConfig struct {
+	// Replica of the source database
+	// +kubebuilder:validation:XValidation:rule="!has(self.timestamp)",message="a standby follows the latest state of the replica, timestamp cannot be set"
+	FromReplica ReplicaDataSource `json:"fromReplica"`
+
+	// Promote the standby to a primary. It stops following the replica, restores it one last
+	// time and starts replicating to its own Litestream replicas. Promotion cannot be undone.
+	Promote bool `json:"promote,omitempty"`
+
+	// Seconds between checks of the replica for new WAL segments
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=1
+	SyncIntervalSeconds int32 `json:"syncIntervalSeconds,omitempty"`
+}
+
+// MaintenanceConfig defines maintenance tasks run on cron schedules, in UTC, by a sidecar of
+// the database pod. The tasks wait for the locks of the writer rather than failing.
+type MaintenanceConfig struct {
+	// PRAGMA quick_check, or integrity_check when full
+	IntegrityCheck *IntegrityCheckTask `json:"integrityCheck,omitempty"`
+
+	// VACUUM, or incremental_vacuum when incremental
+	Vacuum *VacuumTask `json:"vacuum,omitempty"`
+
+	// PRAGMA optimize, or ANALYZE when full
+	Analyze *AnalyzeTask `json:"analyze,omitempty"`
+
+	// PRAGMA wal_checkpoint(TRUNCATE)
+	Checkpoint *CheckpointTask `json:"checkpoint,omitempty"`
+
+	// Seconds a task waits for the locks of the writer before failing
+	// +kubebuilder:default=60
+	// +kubebuilder:validation:Minimum=1
+	BusyTimeoutSeconds int32 `json:"busyTimeoutSeconds,omitempty"`
+
+	// Resource requirements of the maintenance sidecar
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// IntegrityCheckTask defines a scheduled integrity check
+type IntegrityCheckTask struct {
+	// Cron schedule of the task
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Run the full integrity_check instead of quick_check, which skips the index contents
+	Full bool `json:"full,omitempty"`
+}
+
+// VacuumTask defines a scheduled VACUUM
+type VacuumTask struct {
+	// Cron schedule of the task
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Run incremental_vacuum, which only frees pages of a database with auto_vacuum set to
+	// INCREMENTAL, instead of rebuilding the database
+	Incremental bool `json:"incremental,omitempty"`
+
+	// Maximum pages freed by incremental_vacuum, all free pages when 0
+	// +kubebuilder:validation:Minimum=0
+	Pages int32 `json:"pages,omitempty"`
+}
+
+// AnalyzeTask defines a scheduled update of the query planner statistics
+type AnalyzeTask struct {
+	// Cron schedule of the task
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Run ANALYZE on every table instead of PRAGMA optimize, which only analyzes the tables
+	// that need it
+	Full bool `json:"full,omitempty"`
+}
+
+// CheckpointTask defines a scheduled WAL checkpoint
+type CheckpointTask struct {
+	// Cron schedule of the task
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+}
+
+// RecoveryConfig defines how a corrupt database is recovered
+type RecoveryConfig struct {
+	// Check the database when the pod starts, and when it is corrupt move it to a quarantine
+	// directory on the volume and restore the latest state of the Litestream replica. A failed
+	// scheduled integrity check restarts the pod to run the check.
+	AutoRestore bool `json:"autoRestore,omitempty"`
+
+	// Check run when the pod starts, quick (PRAGMA quick_check) or full (PRAGMA integrity_check)
+	// +kubebuilder:validation:Enum=quick;full
+	// +kubebuilder:default=quick
+	IntegrityCheck string `json:"integrityCheck,omitempty"`
+
+	// Corrupt databases kept in quarantine, the oldest are deleted
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	KeepQuarantined int32 `json:"keepQuarantined,omitempty"`
+}
+
+// AgentConfig defines the in-pod agent. The agent runs as a sidecar of the database pod and
+// serves the statistics of the database and the control commands to the operator, with a
+// token stored in the <name>-agent Secret.
+type AgentConfig struct {
+	// Run the agent sidecar
+	// +kubebuilder:default=false
+	Enabled bool `json:"enabled"`
+
+	// Port of the agent
+	// +kubebuilder:default=9090
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port,omitempty"`
+
+	// Resource requirements of the agent sidecar
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// StatisticsConfig defines the refresh of the statistics of the database
+type StatisticsConfig struct {
+	// Seconds between refreshes of status.database and status.lastBackup
+	// +kubebuilder:default=300
+	// +kubebuilder:validation:Minimum=30
+	IntervalSeconds int32 `json:"intervalSeconds,omitempty"`
+}
+
 // SqliteRestConfig defines sqlite-rest API configuration
 type SqliteRestConfig struct {
 	// Enable sqlite-rest API
@@ -183,7 +718,7 @@ type TLSConfig struct {
 // SqliteDatabaseStatus defines the observed state of SqliteDatabase.
 type SqliteDatabaseStatus struct {
 	// Current phase of the database
-	// +kubebuilder:validation:Enum=Pending;Running;Failed;Terminating
+	// +kubebuilder:validation:Enum=Pending;Running;Standby;Failed;Terminating
 	Phase string `json:"phase,omitempty"`
 
 	// Human-readable message about the current status
@@ -192,12 +727,55 @@ type SqliteDatabaseStatus struct {
 	// Number of active replicas
 	Replicas int32 `json:"replicas,omitempty"`
 
-	// Timestamp of the last successful backup
+	// End of the latest Litestream generation across the replicas, the last write replicated
 	LastBackup *metav1.Time `json:"lastBackup,omitempty"`
 
+	// Last time a replica was restored and passed verification
+	LastVerified *metav1.Time `json:"lastVerified,omitempty"`
+
+	// Last write replicated to each Litestream replica
+	// +listType=map
+	// +listMapKey=url
+	Backups []ReplicaBackupStatus `json:"backups,omitempty"`
+
 	// API endpoints information
 	Endpoints *EndpointsStatus `json:"endpoints,omitempty"`
 
+	// Container images resolved for the database pod
+	Images *ImagesStatus `json:"images,omitempty"`
+
+	// Pod currently holding the writer lease
+	Writer string `json:"writer,omitempty"`
+
+	// Size and capacity of the database volume
+	Storage *StorageStatus `json:"storage,omitempty"`
+
+	// Masking rules applied to the data source
+	Masking *MaskingStatus `json:"masking,omitempty"`
+
+	// Number of ready read replica pods
+	ReadReplicas int32 `json:"readReplicas,omitempty"`
+
+	// Primary and replication positions of the LiteFS pods
+	LiteFS *LiteFSStatus `json:"litefs,omitempty"`
+
+	// Replication lag of a standby and the time it was promoted
+	Standby *StandbyStatus `json:"standby,omitempty"`
+
+	// Restores of a corrupt database from the replica
+	Recovery *RecoveryStatus `json:"recovery,omitempty"`
+
+	// State reported by the in-pod agent
+	Agent *AgentStatus `json:"agent,omitempty"`
+
+	// Statistics of the database file
+	Database *DatabaseStatus `json:"database,omitempty"`
+
+	// Last and next run of each maintenance task
+	// +listType=map
+	// +listMapKey=task
+	Maintenance []MaintenanceTaskStatus `json:"maintenance,omitempty"`
+
 	// Conditions represent the latest available observations of an object's state
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
@@ -205,6 +783,227 @@ type SqliteDatabaseStatus struct {
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
+// RecoveryStatus defines the recovery of a corrupt database
+type RecoveryStatus struct {
+	// Time a failed integrity check last restarted the database pod
+	RestartedAt *metav1.Time `json:"restartedAt,omitempty"`
+
+	// Last restore of a corrupt database from the replica
+	LastRestore *RecoveryRestore `json:"lastRestore,omitempty"`
+}
+
+// RecoveryRestore records a corrupt database restored from the replica. The writes made
+// between the end of the replica and the last modification of the corrupt database are lost.
+type RecoveryRestore struct {
+	// Time the corruption was detected
+	DetectedAt metav1.Time `json:"detectedAt"`
+
+	// Output of the failed check
+	Reason string `json:"reason,omitempty"`
+
+	// Path of the corrupt database in quarantine on the volume
+	QuarantinedAs string `json:"quarantinedAs"`
+
+	// Last modification of the corrupt database or its WAL
+	LastModified *metav1.Time `json:"lastModified,omitempty"`
+
+	// End of the replica generation the database was restored from
+	ReplicatedUntil *metav1.Time `json:"replicatedUntil,omitempty"`
+}
+
+// ReplicaBackupStatus defines the last write replicated to a Litestream replica
+type ReplicaBackupStatus struct {
+	// URL of the replica
+	URL string `json:"url"`
+
+	// End of the latest generation of the replica
+	ReplicatedUntil *metav1.Time `json:"replicatedUntil,omitempty"`
+}
+
+// DatabaseStatus defines the statistics of the database file. The page counts, schema version
+// and table count are only reported by the agent or a container with the sqlite3 shell, such
+// as the maintenance sidecar.
+type DatabaseStatus struct {
+	// Size of the database file and its WAL
+	Size string `json:"size,omitempty"`
+
+	// Size of the database file in bytes
+	FileBytes int64 `json:"fileBytes,omitempty"`
+
+	// Size of the WAL in bytes
+	WALBytes int64 `json:"walBytes,omitempty"`
+
+	// PRAGMA page_size
+	PageSize int64 `json:"pageSize,omitempty"`
+
+	// PRAGMA page_count
+	PageCount int64 `json:"pageCount,omitempty"`
+
+	// PRAGMA freelist_count, the unused pages VACUUM would free
+	FreelistCount int64 `json:"freelistCount,omitempty"`
+
+	// PRAGMA user_version, the schema version set by the application
+	SchemaVersion int64 `json:"schemaVersion,omitempty"`
+
+	// Number of tables, without the internal sqlite_ tables
+	TableCount int32 `json:"tableCount,omitempty"`
+
+	// Last modification of the database file or its WAL
+	LastModified *metav1.Time `json:"lastModified,omitempty"`
+
+	// Pod the statistics were collected in
+	Pod string `json:"pod,omitempty"`
+
+	// Time the statistics were collected
+	UpdatedAt *metav1.Time `json:"updatedAt,omitempty"`
+}
+
+// AgentStatus defines the state reported by the in-pod agent
+type AgentStatus struct {
+	// Pod the agent was last polled in
+	Pod string `json:"pod,omitempty"`
+
+	// Last time the agent was polled
+	LastPoll *metav1.Time `json:"lastPoll,omitempty"`
+
+	// Position Litestream copied the WAL up to, generation/index:offset
+	LitestreamPosition string `json:"litestreamPosition,omitempty"`
+
+	// Last time a checkpoint reset the WAL, seen by the agent since it started
+	LastCheckpoint *metav1.Time `json:"lastCheckpoint,omitempty"`
+
+	// Probe queries of the agent itself, for its stats and commands, that timed out on a lock
+	// since it started. The contention of the application and Litestream is not counted.
+	ProbeBusyCount int64 `json:"probeBusyCount,omitempty"`
+
+	// Last control command requested with the sqlite.io/agent-command annotation
+	LastCommand *AgentCommandStatus `json:"lastCommand,omitempty"`
+}
+
+// AgentCommandStatus defines the result of a control command run by the agent
+type AgentCommandStatus struct {
+	// Command, checkpoint, snapshot or quick_check
+	Command string `json:"command"`
+
+	// Time the command ran
+	Time metav1.Time `json:"time"`
+
+	// Result of the command, Succeeded or Failed
+	Result string `json:"result"`
+
+	// Output of the command, such as the path of a snapshot or the problems found by
+	// quick_check
+	Output string `json:"output,omitempty"`
+}
+
+// MaintenanceTaskStatus defines the last and next run of a maintenance task
+type MaintenanceTaskStatus struct {
+	// Task, integrityCheck, vacuum, analyze or checkpoint
+	Task string `json:"task"`
+
+	// Schedule the next run was computed from
+	Schedule string `json:"schedule,omitempty"`
+
+	// Time the task last ran
+	LastRun *metav1.Time `json:"lastRun,omitempty"`
+
+	// Duration of the last run
+	Duration string `json:"duration,omitempty"`
+
+	// Result of the last run, Succeeded or Failed
+	Result string `json:"result,omitempty"`
+
+	// Output of the last run, such as the problems found by an integrity check
+	Message string `json:"message,omitempty"`
+
+	// Time the task runs next
+	NextRun *metav1.Time `json:"nextRun,omitempty"`
+}
+
+// StandbyStatus defines the state of a standby database
+type StandbyStatus struct {
+	// Last time the standby was found in sync with the replica of its source
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// Time since the last sync with the replica, measured when the status was updated. The
+	// replica itself lags the source by the Litestream sync interval of the source.
+	Lag string `json:"lag,omitempty"`
+
+	// Time the standby was promoted to a primary
+	PromotedAt *metav1.Time `json:"promotedAt,omitempty"`
+}
+
+// LiteFSStatus defines the state of the LiteFS pods
+type LiteFSStatus struct {
+	// Pod currently elected primary
+	Primary string `json:"primary,omitempty"`
+
+	// Replication position of each ready pod
+	Pods []LiteFSPodStatus `json:"pods,omitempty"`
+}
+
+// LiteFSPodStatus defines the replication position of a LiteFS pod
+type LiteFSPodStatus struct {
+	// Name of the pod
+	Name string `json:"name"`
+
+	// Role of the pod, primary or replica
+	Role string `json:"role"`
+
+	// Position of the database on the pod, as the transaction ID and checksum from the
+	// LiteFS -pos file. A replica has caught up when its position equals the primary's.
+	Position string `json:"position,omitempty"`
+}
+
+// MaskingStatus records the masking rules applied when the database was created
+type MaskingStatus struct {
+	// SHA-256 of the masking rules, as JSON, applied to the data source
+	RulesHash string `json:"rulesHash"`
+
+	// Number of rules applied
+	Rules int32 `json:"rules"`
+
+	// Time the masked database was created
+	AppliedAt metav1.Time `json:"appliedAt"`
+}
+
+// StorageStatus defines the size of the database volume
+type StorageStatus struct {
+	// Size requested by the PersistentVolumeClaim
+	Requested string `json:"requested,omitempty"`
+
+	// Capacity of the bound volume
+	Capacity string `json:"capacity,omitempty"`
+
+	// Size of the database file and its WAL, measured when autoGrow is enabled
+	Used string `json:"used,omitempty"`
+
+	// Expansions made by autoGrow, most recent last
+	History []StorageGrowth `json:"history,omitempty"`
+
+	// Claim holding the database after a StorageClass migration, <name>-db-storage otherwise.
+	// Also recorded in the sqlite.io/database-claim annotation.
+	Claim string `json:"claim,omitempty"`
+
+	// Claim holding the database before the last StorageClass migration, retained until confirmed
+	PreviousClaim string `json:"previousClaim,omitempty"`
+}
+
+// StorageGrowth records an automatic expansion of the database volume
+type StorageGrowth struct {
+	// Time of the expansion
+	Time metav1.Time `json:"time"`
+
+	// Size of the volume before the expansion
+	From string `json:"from"`
+
+	// Size of the volume after the expansion
+	To string `json:"to"`
+
+	// Size of the database file and its WAL that triggered the expansion
+	Used string `json:"used"`
+}
+
 // EndpointsStatus defines API endpoints information
 type EndpointsStatus struct {
 	// REST API endpoint URL
@@ -212,10 +1011,35 @@ type EndpointsStatus struct {
 
 	// Metrics endpoint URL
 	Metrics *string `json:"metrics,omitempty"`
+
+	// REST API endpoint URL of the LiteFS primary, accepting writes
+	Primary *string `json:"primary,omitempty"`
+}
+
+// ImagesStatus defines the container images in use
+type ImagesStatus struct {
+	// Image used by the init container
+	Sqlite string `json:"sqlite,omitempty"`
+
+	// Image used by the Litestream container
+	Litestream string `json:"litestream,omitempty"`
+
+	// Image used by the sqlite-rest container
+	SqliteRest string `json:"sqliteRest,omitempty"`
+
+	// Image used by the writer lease and LiteFS election containers
+	Operator string `json:"operator,omitempty"`
+
+	// Image used by the litefs container
+	LiteFS string `json:"litefs,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Size",type=string,JSONPath=`.status.database.size`,priority=1
+// +kubebuilder:printcolumn:name="Last Backup",type=date,JSONPath=`.status.lastBackup`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // SqliteDatabase is the Schema for the sqlitedatabases API.
 type SqliteDatabase struct {
@@ -0,0 +1,116 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command agent serves the statistics of a database and runs control commands on it. It runs
+// as a sidecar of the database pod in the sqlite image, which it is copied into by an init
+// container of the operator image with --install.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/sqlite-operator/sqlite-operator/internal/agent"
+)
+
+var setupLog = ctrl.Log.WithName("agent")
+
+func main() {
+	var config agent.Config
+	var install, listen string
+	flag.StringVar(&install, "install", "", "Copy the agent to this path and exit.")
+	flag.StringVar(&config.Path, "db", "", "The path of the database file.")
+	flag.StringVar(&config.Shell, "sqlite", "sqlite3", "The sqlite3 or sqlcipher binary running the SQL.")
+	flag.DurationVar(&config.BusyTimeout, "busy-timeout", 5*time.Second, "How long a statement waits for the locks of the writer.")
+	flag.StringVar(&config.SnapshotDir, "snapshot-dir", "", "The directory receiving the snapshots of the database.")
+	flag.StringVar(&listen, "listen", ":9090", "The address the agent listens on.")
+	opts := zap.Options{}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	if install != "" {
+		if err := copyExecutable(install); err != nil {
+			setupLog.Error(err, "unable to install agent", "path", install)
+			os.Exit(1)
+		}
+		setupLog.Info("agent installed", "path", install)
+		return
+	}
+
+	if config.Path == "" {
+		setupLog.Error(errors.New("missing flags"), "--db is required")
+		os.Exit(1)
+	}
+
+	// The token and the key come from the environment so that they stay out of the pod spec
+	token := os.Getenv("AGENT_TOKEN")
+	if token == "" {
+		setupLog.Error(errors.New("missing token"), "AGENT_TOKEN is required")
+		os.Exit(1)
+	}
+	config.Key = os.Getenv("SQLITE_ENCRYPTION_KEY")
+
+	ctx := ctrl.SetupSignalHandler()
+	a := agent.New(config)
+	go a.Watch(ctx, 10*time.Second)
+
+	server := &http.Server{Addr: listen, Handler: a.Handler(token), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	setupLog.Info("serving agent", "address", listen, "db", config.Path)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		setupLog.Error(err, "unable to serve agent")
+		os.Exit(1)
+	}
+}
+
+// copyExecutable copies the running binary to the path
+func copyExecutable(path string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(self)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
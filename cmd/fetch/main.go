@@ -0,0 +1,72 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command fetch downloads the data source of a database from an HTTP(S) or S3 URL. It runs
+// as an init container before the database is created, and does nothing once it exists.
+package main
+
+import (
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/sqlite-operator/sqlite-operator/internal/fetch"
+)
+
+var setupLog = ctrl.Log.WithName("fetch")
+
+func main() {
+	var config fetch.Config
+	var skipIfExists string
+	flag.StringVar(&config.URL, "url", "", "The http://, https:// or s3://bucket/key URL of the file.")
+	flag.StringVar(&config.Output, "output", "", "The path the file is written to.")
+	flag.StringVar(&config.SHA256, "sha256", "", "The expected SHA-256 checksum of the file.")
+	flag.StringVar(&config.Region, "region", "", "The region of the S3 bucket.")
+	flag.StringVar(&config.Endpoint, "endpoint", "", "The endpoint of an S3-compatible service.")
+	flag.StringVar(&skipIfExists, "skip-if-exists", "", "Do nothing if this path exists, usually the database file.")
+	opts := zap.Options{}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	if config.URL == "" || config.Output == "" || config.SHA256 == "" {
+		setupLog.Error(errors.New("missing flags"), "--url, --output and --sha256 are required")
+		os.Exit(1)
+	}
+
+	if skipIfExists != "" {
+		if _, err := os.Stat(skipIfExists); err == nil {
+			setupLog.Info("database exists, skipping data source", "path", skipIfExists)
+			return
+		}
+	}
+
+	// Credentials come from the environment so that they stay out of the pod spec
+	config.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	config.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+
+	setupLog.Info("fetching data source", "url", config.URL)
+	if err := fetch.Fetch(ctrl.SetupSignalHandler(), http.DefaultClient, config); err != nil {
+		setupLog.Error(err, "unable to fetch data source")
+		os.Exit(1)
+	}
+	setupLog.Info("data source verified", "output", config.Output)
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command lease holds the writer lease of a database pod. It runs as a native sidecar in
+// "hold" mode, acquiring and renewing the lease and serving its state for the startup probe
+// blocking the init containers and the writers' liveness probes.
+// In "elect" mode it campaigns for the LiteFS primary, writing the role of the pod to a
+// file read by the litefs container and labelling the pod for the primary Service, and
+// deletes the pod when its role changes for it to be recreated with the new role.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/sqlite-operator/sqlite-operator/internal/lease"
+)
+
+var setupLog = ctrl.Log.WithName("lease")
+
+func main() {
+	var mode string
+	var healthAddr string
+	var roleFile string
+	var roleLabel string
+	var peers string
+	var restartGrace time.Duration
+	var config lease.Config
+	flag.StringVar(&mode, "mode", "hold", "Either \"hold\" to keep renewing the lease until terminated, "+
+		"or \"elect\" to campaign for it until terminated.")
+	flag.StringVar(&config.Namespace, "namespace", "", "The namespace of the Lease.")
+	flag.StringVar(&config.Name, "lease-name", "", "The name of the Lease.")
+	flag.StringVar(&config.Identity, "identity", "", "The identity of the holder, usually the pod name.")
+	flag.DurationVar(&config.LeaseDuration, "lease-duration", 15*time.Second,
+		"The duration non-holders wait before taking over the lease.")
+	flag.DurationVar(&config.RenewDeadline, "renew-deadline", 10*time.Second,
+		"The duration the holder retries renewing the lease before giving it up.")
+	flag.DurationVar(&config.RetryPeriod, "retry-period", 2*time.Second,
+		"The duration between attempts to acquire or renew the lease.")
+	flag.StringVar(&healthAddr, "health-addr", ":8095", "The address serving the lease state in hold mode.")
+	flag.StringVar(&roleFile, "role-file", "", "The file the role of the pod, primary or replica, is written to in elect mode.")
+	flag.StringVar(&roleLabel, "role-label", "", "The label set to the role of the pod in elect mode.")
+	flag.DurationVar(&restartGrace, "restart-grace", 30*time.Second, "The duration the lease is extended by "+
+		"while the pod restarts to be promoted in elect mode.")
+	flag.StringVar(&peers, "peers", "", "The comma-separated pods whose role label is reset to replica "+
+		"when this pod is elected, as a failed primary cannot reset its own.")
+	opts := zap.Options{}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	if config.Namespace == "" || config.Name == "" || config.Identity == "" {
+		setupLog.Error(errors.New("missing flags"), "--namespace, --lease-name and --identity are required")
+		os.Exit(1)
+	}
+
+	clientset, err := kubernetes.NewForConfig(ctrl.GetConfigOrDie())
+	if err != nil {
+		setupLog.Error(err, "unable to create client")
+		os.Exit(1)
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+	holder := lease.NewHolder(clientset, config)
+
+	switch mode {
+	case "hold":
+		server := &http.Server{Addr: healthAddr, Handler: holder, ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				setupLog.Error(err, "problem serving lease state")
+				os.Exit(1)
+			}
+		}()
+
+		setupLog.Info("holding writer lease", "lease", config.Name, "identity", config.Identity)
+		err := holder.Hold(ctx)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = server.Shutdown(shutdownCtx)
+		cancel()
+
+		if err != nil {
+			setupLog.Error(err, "the writers will be stopped by their liveness probes")
+			os.Exit(1)
+		}
+		setupLog.Info("released writer lease")
+	case "elect":
+		setupLog.Info("campaigning for primary", "lease", config.Name, "identity", config.Identity)
+		if err := elect(ctx, clientset, holder, config, roleFile, roleLabel, peers, restartGrace); err != nil {
+			setupLog.Error(err, "stepped down as primary")
+			os.Exit(1)
+		}
+		setupLog.Info("stopped campaigning for primary")
+	default:
+		setupLog.Error(errors.New("unknown mode"), "mode must be hold or elect", "mode", mode)
+		os.Exit(1)
+	}
+}
+
+// elect campaigns for the LiteFS primary. LiteFS is mounted once with the role read from the
+// role file, so the role is only written once the holder of the lease is known, and a pod whose
+// role changes afterwards is deleted to be recreated with the new role rather than remounting
+// LiteFS under the containers using it.
+func elect(ctx context.Context, clientset kubernetes.Interface, holder *lease.Holder, config lease.Config,
+	roleFile, roleLabel, peers string, restartGrace time.Duration) error {
+	electCtx, stopElecting := context.WithCancel(ctx)
+	defer stopElecting()
+
+	var mu sync.Mutex
+	decided := false
+	// The role LiteFS was mounted with outlives restarts of this container
+	mounted := readRole(roleFile)
+	restart := ""
+	settle := func(role string) {
+		switch {
+		case restart != "":
+		case mounted == "":
+			setupLog.Info("elected role", "role", role)
+			mounted = role
+			if err := publishRole(clientset, config, roleFile, roleLabel, role); err != nil {
+				setupLog.Error(err, "unable to publish role", "role", role)
+			}
+			if role == "primary" {
+				resetPeers(clientset, config, roleLabel, peers)
+			}
+		case role != mounted:
+			setupLog.Info("role changed, restarting pod", "mounted", mounted, "role", role)
+			restart = role
+			// A promoted pod keeps the lease while it restarts
+			if role == "primary" {
+				holder.Keep()
+			}
+			stopElecting()
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- holder.Elect(electCtx, func(primary bool) {
+			mu.Lock()
+			defer mu.Unlock()
+			// The lease is released on shutdown, the pod is terminating already
+			if decided && ctx.Err() == nil {
+				settle(roleName(primary))
+			}
+		})
+	}()
+
+	// The election is decided once the holder is known and the callbacks caught up with it
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	var err error
+	for waiting := true; waiting; {
+		select {
+		case err = <-done:
+			waiting = false
+		case <-ticker.C:
+			mu.Lock()
+			if leader := holder.Leader(); !decided && leader != "" && (leader == config.Identity) == holder.Held() {
+				decided = true
+				settle(roleName(holder.Held()))
+			}
+			mu.Unlock()
+		}
+	}
+
+	mu.Lock()
+	role := restart
+	mu.Unlock()
+	if role == "" {
+		return err
+	}
+
+	// The pod is recreated by its StatefulSet, the lease being extended to cover the restart
+	// so that no other pod is elected meanwhile
+	if role == "primary" {
+		if err := holder.Extend(config.LeaseDuration + restartGrace); err != nil {
+			setupLog.Error(err, "unable to extend lease for restart")
+		}
+	}
+	deleteCtx, cancel := context.WithTimeout(context.Background(), config.RenewDeadline)
+	defer cancel()
+	if err := clientset.CoreV1().Pods(config.Namespace).Delete(deleteCtx, config.Identity,
+		metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// roleName returns the role published for the pod
+func roleName(primary bool) string {
+	if primary {
+		return "primary"
+	}
+	return "replica"
+}
+
+// readRole returns the role in the role file, empty if it was not written yet
+func readRole(roleFile string) string {
+	data, err := os.ReadFile(roleFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// resetPeers sets the role label of the other pods to replica, as a failed primary cannot
+// reset its own
+func resetPeers(clientset kubernetes.Interface, config lease.Config, roleLabel, peers string) {
+	if roleLabel == "" || peers == "" {
+		return
+	}
+	for _, peer := range strings.Split(peers, ",") {
+		if peer == config.Identity {
+			continue
+		}
+		peerConfig := config
+		peerConfig.Identity = peer
+		if err := publishRole(clientset, peerConfig, "", roleLabel, "replica"); err != nil && !apierrors.IsNotFound(err) {
+			setupLog.Error(err, "unable to reset role of peer", "pod", peer)
+		}
+	}
+}
+
+// publishRole writes the role to the role file, replacing it atomically so that it is never
+// read partially, and sets the role label on the pod
+func publishRole(clientset kubernetes.Interface, config lease.Config, roleFile, roleLabel, role string) error {
+	if roleFile != "" {
+		tmp := filepath.Join(filepath.Dir(roleFile), "."+filepath.Base(roleFile))
+		if err := os.WriteFile(tmp, []byte(role+"\n"), 0o644); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, roleFile); err != nil {
+			return err
+		}
+	}
+
+	if roleLabel == "" {
+		return nil
+	}
+	// The role is published from the election callbacks, which have no context
+	ctx, cancel := context.WithTimeout(context.Background(), config.RenewDeadline)
+	defer cancel()
+	patch := fmt.Sprintf(`{"metadata":{"labels":{%q:%q}}}`, roleLabel, role)
+	return retry.OnError(retry.DefaultBackoff, func(err error) bool {
+		return ctx.Err() == nil && !apierrors.IsNotFound(err)
+	}, func() error {
+		_, err := clientset.CoreV1().Pods(config.Namespace).Patch(ctx, config.Identity,
+			types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+		return err
+	})
+}
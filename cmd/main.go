@@ -19,8 +19,10 @@ package main
 import (
 	"crypto/tls"
 	"flag"
+	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -28,6 +30,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
@@ -38,7 +41,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+	"github.com/sqlite-operator/sqlite-operator/internal/agent"
 	"github.com/sqlite-operator/sqlite-operator/internal/controller"
+	webhookv1 "github.com/sqlite-operator/sqlite-operator/internal/webhook/v1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -63,7 +68,9 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var enableWebhooks bool
 	var tlsOpts []func(*tls.Config)
+	var images controller.Images
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -81,6 +88,22 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"If set, the pod webhook injecting the database sidecars is served. It requires the webhook certificate.")
+	flag.StringVar(&images.Sqlite, "sqlite-image", envOrDefault("RELATED_IMAGE_SQLITE", controller.DefaultSqliteImage),
+		"The default image providing the sqlite3 CLI for the init container.")
+	flag.StringVar(&images.Litestream, "litestream-image",
+		envOrDefault("RELATED_IMAGE_LITESTREAM", controller.DefaultLitestreamImage),
+		"The default Litestream image.")
+	flag.StringVar(&images.SqliteRest, "sqlite-rest-image",
+		envOrDefault("RELATED_IMAGE_SQLITE_REST", controller.DefaultSqliteRestImage),
+		"The default sqlite-rest image.")
+	flag.StringVar(&images.SqlcipherSqlite, "sqlcipher-image", os.Getenv("RELATED_IMAGE_SQLCIPHER"),
+		"The default image providing the sqlcipher CLI for encrypted databases, built from Dockerfile.sqlcipher.")
+	flag.StringVar(&images.LiteFS, "litefs-image", envOrDefault("RELATED_IMAGE_LITEFS", controller.DefaultLiteFSImage),
+		"The default LiteFS image.")
+	flag.StringVar(&images.Operator, "operator-image", envOrDefault("OPERATOR_IMAGE", controller.DefaultOperatorImage),
+		"The image of the operator, used for the writer lease and LiteFS election containers of database pods.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -202,13 +225,53 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := (&controller.SqliteDatabaseReconciler{
+	// Run Litestream as a native sidecar where the cluster supports it
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create discovery client")
+		os.Exit(1)
+	}
+	nativeSidecars := false
+	if serverVersion, err := discoveryClient.ServerVersion(); err != nil {
+		setupLog.Error(err, "unable to get server version, running Litestream as a regular container")
+	} else {
+		nativeSidecars = controller.NativeSidecarsSupported(serverVersion)
+		setupLog.Info("detected server version", "version", serverVersion.GitVersion, "nativeSidecars", nativeSidecars)
+	}
+
+	// Measure the database files in the pods for storage autoGrow
+	executor, err := controller.NewPodExecutor(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create pod executor")
+		os.Exit(1)
+	}
+
+	reconciler := &controller.SqliteDatabaseReconciler{
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Images:         images,
+		NativeSidecars: nativeSidecars,
+		Executor:       executor,
+		Recorder:       mgr.GetEventRecorderFor("sqlitedatabase-controller"),
+		Agent:          &agent.Client{HTTP: &http.Client{Timeout: 30 * time.Second}},
+	}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "SqliteDatabase")
+		os.Exit(1)
+	}
+	if err := (&controller.SqliteCloneReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "SqliteDatabase")
+		setupLog.Error(err, "unable to create controller", "controller", "SqliteClone")
 		os.Exit(1)
 	}
+	if enableWebhooks {
+		if err := webhookv1.SetupPodWebhookWithManager(mgr, reconciler); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Pod")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
@@ -242,3 +305,11 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// envOrDefault returns the value of the environment variable key, or defaultValue if it is unset.
+func envOrDefault(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,43 @@
+#!/usr/bin/env bash
+# Pins the default images of the database pods to the digests their tags currently point to,
+# in the Go defaults and in the RELATED_IMAGE_* values of the manager. Run it with registry
+# access before each release: make pin-images
+#
+# With --check, it only fails if a reference has no digest, without registry access:
+# make check-pinned-images
+set -euo pipefail
+
+CRANE=${CRANE:-crane}
+cd "$(dirname "$0")/.."
+
+files=(internal/controller/sqlitedatabase_controller.go config/manager/manager.yaml)
+
+# The references are the Default*Image constants and the RELATED_IMAGE_* values, with or
+# without a previous digest
+refs=$(
+	grep -hoE '^\s*Default[A-Za-z]+Image\s*=\s*"[^"]+"' internal/controller/sqlitedatabase_controller.go |
+		sed -E 's/.*"([^"]+)"/\1/'
+	grep -A1 -E 'name: RELATED_IMAGE_' config/manager/manager.yaml |
+		sed -nE 's/^\s*value: ([^ #]+).*/\1/p'
+)
+
+if [ "${1:-}" = "--check" ]; then
+	unpinned=$(echo "$refs" | grep -v '@sha256:' | sort -u || true)
+	if [ -n "$unpinned" ]; then
+		echo "Default images without a digest, run make pin-images:" >&2
+		echo "$unpinned" >&2
+		exit 1
+	fi
+	exit 0
+fi
+
+for ref in $(echo "$refs" | sort -u); do
+	tagged=${ref%@*}
+	digest=$("$CRANE" digest "$tagged")
+	pinned="$tagged@$digest"
+	if [ "$ref" != "$pinned" ]; then
+		echo "$tagged -> $digest"
+		# References are matched literally, between quotes or whitespace and the end of the value
+		FROM=$ref TO=$pinned perl -pi -e 's/(?<=["\s])\Q$ENV{FROM}\E(?=["\s]|$)/$ENV{TO}/g' "${files[@]}"
+	fi
+done
@@ -0,0 +1,450 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package agent serves the statistics of a database and runs control commands on it from a
+// sidecar of the database pod. SQL runs through the sqlite3 or sqlcipher shell of the
+// sidecar image, which opens the database next to the writer and waits for its locks, so
+// that the operator needs no SQLite library.
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Control commands accepted by the agent
+const (
+	// CommandCheckpoint checkpoints the WAL and truncates it
+	CommandCheckpoint = "checkpoint"
+	// CommandSnapshot writes a consistent copy of the database to the snapshot directory
+	CommandSnapshot = "snapshot"
+	// CommandQuickCheck runs PRAGMA quick_check
+	CommandQuickCheck = "quick_check"
+)
+
+// ErrUnknownCommand is returned for a command the agent does not implement
+var ErrUnknownCommand = errors.New("unknown command")
+
+// Config configures the agent
+type Config struct {
+	// Path of the database file
+	Path string
+	// Shell is the sqlite3 or sqlcipher binary running the SQL
+	Shell string
+	// Key of an encrypted database, empty otherwise
+	Key string
+	// BusyTimeout is how long a statement waits for the locks of the writer
+	BusyTimeout time.Duration
+	// SnapshotDir receives the snapshots of the database
+	SnapshotDir string
+}
+
+// Stats are the statistics of the database
+type Stats struct {
+	// DatabaseBytes is the size of the database file
+	DatabaseBytes int64 `json:"databaseBytes"`
+	// WALBytes is the size of the WAL, 0 without one
+	WALBytes int64 `json:"walBytes"`
+	// PageSize, PageCount and FreelistCount are the results of the PRAGMAs
+	PageSize      int64 `json:"pageSize"`
+	PageCount     int64 `json:"pageCount"`
+	FreelistCount int64 `json:"freelistCount"`
+	// UserVersion is the schema version set by the application
+	UserVersion int64 `json:"userVersion"`
+	// Tables are the names of the tables, without the internal sqlite_ tables
+	Tables []string `json:"tables"`
+	// LastModified is the last modification of the database file or its WAL
+	LastModified time.Time `json:"lastModified"`
+	// Litestream is the position of the shadow WAL of Litestream, nil without Litestream
+	Litestream *LitestreamPosition `json:"litestream,omitempty"`
+	// LastCheckpoint is the last time the WAL was reset by a checkpoint, nil until one is seen
+	LastCheckpoint *time.Time `json:"lastCheckpoint,omitempty"`
+	// ProbeBusyCount is the number of queries of the agent itself that timed out on a lock
+	// since it started. The contention of the application and Litestream is not counted.
+	ProbeBusyCount int64 `json:"probeBusyCount"`
+}
+
+// LitestreamPosition is the position Litestream copied the WAL up to
+type LitestreamPosition struct {
+	Generation string `json:"generation"`
+	Index      int64  `json:"index"`
+	Offset     int64  `json:"offset"`
+}
+
+// String formats the position like Litestream, generation/index:offset
+func (p LitestreamPosition) String() string {
+	return fmt.Sprintf("%s/%08x:%d", p.Generation, p.Index, p.Offset)
+}
+
+// CommandResult is the result of a control command
+type CommandResult struct {
+	Command   string `json:"command"`
+	Succeeded bool   `json:"succeeded"`
+	// Output of the command, or why it failed
+	Output   string `json:"output"`
+	Duration string `json:"duration"`
+}
+
+// Agent serves the statistics of a database and runs control commands on it
+type Agent struct {
+	config    Config
+	probeBusy atomic.Int64
+
+	mu             sync.Mutex
+	lastCheckpoint *time.Time
+	walObserved    bool
+	walEmpty       bool
+	walHeader      []byte
+}
+
+// New returns an agent for the database of the config
+func New(config Config) *Agent {
+	if config.Shell == "" {
+		config.Shell = "sqlite3"
+	}
+	return &Agent{config: config}
+}
+
+// query runs the SQL with the shell and returns its output
+func (a *Agent) query(ctx context.Context, sql string) (string, error) {
+	var input bytes.Buffer
+	if a.config.Key != "" {
+		// The output of PRAGMA key is discarded so that only the result of the SQL remains
+		fmt.Fprintf(&input, ".output /dev/null\nPRAGMA key = '%s';\n.output stdout\n",
+			strings.ReplaceAll(a.config.Key, "'", "''"))
+	}
+	input.WriteString(sql)
+	input.WriteString("\n")
+
+	cmd := exec.CommandContext(ctx, a.config.Shell, "-bail",
+		"-cmd", fmt.Sprintf(".timeout %d", a.config.BusyTimeout.Milliseconds()), a.config.Path)
+	cmd.Stdin = &input
+	output, err := cmd.CombinedOutput()
+	result := strings.TrimSpace(string(output))
+	if err != nil {
+		if strings.Contains(result, "database is locked") {
+			a.probeBusy.Add(1)
+		}
+		if result == "" {
+			return "", err
+		}
+		return "", fmt.Errorf("%s: %w", result, err)
+	}
+
+	return result, nil
+}
+
+// Stats returns the statistics of the database
+func (a *Agent) Stats(ctx context.Context) (*Stats, error) {
+	info, err := os.Stat(a.config.Path)
+	if err != nil {
+		return nil, err
+	}
+	stats := &Stats{
+		DatabaseBytes: info.Size(),
+		LastModified:  info.ModTime().UTC(),
+		Tables:        []string{},
+		Litestream:    litestreamPosition(a.config.Path),
+	}
+	if wal, err := os.Stat(a.config.Path + "-wal"); err == nil {
+		stats.WALBytes = wal.Size()
+		if wal.ModTime().After(stats.LastModified) {
+			stats.LastModified = wal.ModTime().UTC()
+		}
+	}
+
+	output, err := a.query(ctx, `PRAGMA page_size;
+PRAGMA page_count;
+PRAGMA freelist_count;
+PRAGMA user_version;
+SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite\_%' ESCAPE '\' ORDER BY name;`)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(output, "\n")
+	if len(lines) < 4 {
+		return nil, fmt.Errorf("unexpected output %q", output)
+	}
+	for i, value := range []*int64{&stats.PageSize, &stats.PageCount, &stats.FreelistCount, &stats.UserVersion} {
+		if *value, err = strconv.ParseInt(strings.TrimSpace(lines[i]), 10, 64); err != nil {
+			return nil, fmt.Errorf("unexpected output %q", lines[i])
+		}
+	}
+	for _, table := range lines[4:] {
+		if table = strings.TrimSpace(table); table != "" {
+			stats.Tables = append(stats.Tables, table)
+		}
+	}
+
+	a.mu.Lock()
+	stats.LastCheckpoint = a.lastCheckpoint
+	a.mu.Unlock()
+	stats.ProbeBusyCount = a.probeBusy.Load()
+
+	return stats, nil
+}
+
+// Run runs a control command. A command that ran and failed is reported in the result, an
+// error is only returned for an unknown command.
+func (a *Agent) Run(ctx context.Context, command string) (*CommandResult, error) {
+	start := time.Now()
+	result := &CommandResult{Command: command}
+
+	var err error
+	switch command {
+	case CommandCheckpoint:
+		// The result is busy|log|checkpointed, busy when a reader kept the WAL from being reset
+		if result.Output, err = a.query(ctx, "PRAGMA wal_checkpoint(TRUNCATE);"); err == nil {
+			result.Succeeded = strings.HasPrefix(result.Output, "0|")
+			if result.Succeeded {
+				a.recordCheckpoint(time.Now().UTC())
+			}
+		}
+	case CommandQuickCheck:
+		if result.Output, err = a.query(ctx, "PRAGMA quick_check;"); err == nil {
+			result.Succeeded = result.Output == "ok"
+		}
+	case CommandSnapshot:
+		if result.Output, err = a.snapshot(ctx); err == nil {
+			result.Succeeded = true
+		}
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownCommand, command)
+	}
+	if err != nil {
+		result.Output = err.Error()
+	}
+	result.Duration = time.Since(start).Round(time.Millisecond).String()
+
+	return result, nil
+}
+
+// snapshot writes a consistent copy of the database with VACUUM INTO, replacing the previous
+// snapshot only once complete, and returns its path
+func (a *Agent) snapshot(ctx context.Context) (string, error) {
+	if err := os.MkdirAll(a.config.SnapshotDir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(a.config.SnapshotDir, filepath.Base(a.config.Path))
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	if _, err := a.query(ctx, fmt.Sprintf("VACUUM INTO '%s';", strings.ReplaceAll(tmp, "'", "''"))); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// recordCheckpoint records a checkpoint that reset the WAL
+func (a *Agent) recordCheckpoint(t time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastCheckpoint = &t
+}
+
+// Watch records the checkpoints of the writer until the context is done, noticed when the
+// WAL is truncated or its header changes. A checkpoint is only seen once the WAL is reset,
+// so the last checkpoint is at most one interval late.
+func (a *Agent) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		a.observeWAL(time.Now().UTC())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// observeWAL compares the WAL header, its checkpoint sequence and salt, with the last one seen
+func (a *Agent) observeWAL(now time.Time) {
+	header := make([]byte, 8)
+	empty := true
+	if f, err := os.Open(a.config.Path + "-wal"); err == nil {
+		var raw [24]byte
+		if _, err := io.ReadFull(f, raw[:]); err == nil && binary.BigEndian.Uint32(raw[0:4])&^1 == 0x377f0682 {
+			copy(header, raw[12:20])
+			empty = false
+		}
+		_ = f.Close()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.walObserved {
+		switch {
+		case empty && !a.walEmpty:
+			a.lastCheckpoint = &now
+		case !empty && !a.walEmpty && !bytes.Equal(header, a.walHeader):
+			a.lastCheckpoint = &now
+		}
+	}
+	a.walObserved = true
+	a.walEmpty = empty
+	if !empty {
+		a.walHeader = header
+	}
+}
+
+// litestreamPosition reads the position of Litestream from its metadata directory next to
+// the database, nil if Litestream has not replicated it
+func litestreamPosition(path string) *LitestreamPosition {
+	dir := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+"-litestream")
+	generation, err := os.ReadFile(filepath.Join(dir, "generation"))
+	if err != nil {
+		return nil
+	}
+	position := &LitestreamPosition{Generation: strings.TrimSpace(string(generation))}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "generations", position.Generation, "wal"))
+	if err != nil {
+		return position
+	}
+	var last os.DirEntry
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".wal")
+		if !ok {
+			continue
+		}
+		index, err := strconv.ParseInt(name, 16, 64)
+		if err != nil || (last != nil && index < position.Index) {
+			continue
+		}
+		position.Index = index
+		last = entry
+	}
+	if last != nil {
+		if info, err := last.Info(); err == nil {
+			position.Offset = info.Size()
+		}
+	}
+
+	return position
+}
+
+// Handler serves the health of the agent on /healthz, and the statistics on GET /stats and
+// the control commands on POST /commands/{command} to requests with the bearer token
+func (a *Agent) Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.Handle("GET /stats", authorize(token, func(w http.ResponseWriter, r *http.Request) {
+		stats, err := a.Stats(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, stats)
+	}))
+	mux.Handle("POST /commands/{command}", authorize(token, func(w http.ResponseWriter, r *http.Request) {
+		result, err := a.Run(r.Context(), r.PathValue("command"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, result)
+	}))
+	return mux
+}
+
+// authorize rejects the requests without the bearer token
+func authorize(token string, next http.HandlerFunc) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// writeJSON writes the value as the JSON response
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// Client calls the agents of the database pods
+type Client struct {
+	HTTP *http.Client
+}
+
+// Stats returns the statistics served by the agent at the endpoint, such as http://10.0.0.1:9090
+func (c *Client) Stats(ctx context.Context, endpoint, token string) (*Stats, error) {
+	stats := &Stats{}
+	if err := c.do(ctx, http.MethodGet, endpoint+"/stats", token, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Command runs the control command with the agent at the endpoint
+func (c *Client) Command(ctx context.Context, endpoint, token, command string) (*CommandResult, error) {
+	result := &CommandResult{}
+	if err := c.do(ctx, http.MethodPost, endpoint+"/commands/"+command, token, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// do sends the request and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, url, token string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
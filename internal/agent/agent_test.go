@@ -0,0 +1,211 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeShell writes a shell script standing in for sqlite3 that prints the output
+func fakeShell(t *testing.T, output string, code int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sqlite3")
+	script := fmt.Sprintf("#!/bin/sh\ncat > /dev/null\nprintf '%s'\nexit %d\n", output, code)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+// newDatabase creates an empty database file in a temporary directory
+func newDatabase(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.db")
+	if err := os.WriteFile(path, make([]byte, 8192), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func TestStatsParsesShellOutput(t *testing.T) {
+	path := newDatabase(t)
+	walDir := filepath.Join(filepath.Dir(path), ".app.db-litestream", "generations", "0123456789abcdef", "wal")
+	if err := os.MkdirAll(walDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	for name, size := range map[string]int{"00000001.wal": 10, "0000000a.wal": 42} {
+		if err := os.WriteFile(filepath.Join(walDir, name), make([]byte, size), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	generation := filepath.Join(filepath.Dir(path), ".app.db-litestream", "generation")
+	if err := os.WriteFile(generation, []byte("0123456789abcdef\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	a := New(Config{Path: path, Shell: fakeShell(t, `4096\n2\n1\n7\nposts\nusers\n`, 0)})
+	stats, err := a.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.DatabaseBytes != 8192 || stats.PageSize != 4096 || stats.PageCount != 2 ||
+		stats.FreelistCount != 1 || stats.UserVersion != 7 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+	if !reflect.DeepEqual(stats.Tables, []string{"posts", "users"}) {
+		t.Errorf("Tables = %v, want [posts users]", stats.Tables)
+	}
+	want := LitestreamPosition{Generation: "0123456789abcdef", Index: 10, Offset: 42}
+	if stats.Litestream == nil || *stats.Litestream != want {
+		t.Errorf("Litestream = %v, want %v", stats.Litestream, want)
+	}
+}
+
+func TestQueryCountsBusyProbes(t *testing.T) {
+	a := New(Config{Path: newDatabase(t), Shell: fakeShell(t, `Error: database is locked\n`, 5)})
+	if _, err := a.Stats(context.Background()); err == nil {
+		t.Fatal("Stats() succeeded, want an error")
+	}
+	if got := a.probeBusy.Load(); got != 1 {
+		t.Errorf("probeBusy = %d, want 1", got)
+	}
+}
+
+func TestObserveWALRecordsCheckpoints(t *testing.T) {
+	path := newDatabase(t)
+	a := New(Config{Path: path})
+	writeWAL := func(sequence uint32) {
+		header := make([]byte, 32)
+		binary.BigEndian.PutUint32(header[0:4], 0x377f0682)
+		binary.BigEndian.PutUint32(header[12:16], sequence)
+		if err := os.WriteFile(path+"-wal", header, 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	start := time.Date(2025, time.January, 15, 10, 0, 0, 0, time.UTC)
+
+	writeWAL(1)
+	a.observeWAL(start)
+	if a.lastCheckpoint != nil {
+		t.Fatalf("lastCheckpoint = %v after the first observation, want nil", a.lastCheckpoint)
+	}
+	a.observeWAL(start.Add(time.Minute))
+	if a.lastCheckpoint != nil {
+		t.Fatalf("lastCheckpoint = %v for an unchanged WAL, want nil", a.lastCheckpoint)
+	}
+
+	writeWAL(2)
+	a.observeWAL(start.Add(2 * time.Minute))
+	if a.lastCheckpoint == nil || !a.lastCheckpoint.Equal(start.Add(2*time.Minute)) {
+		t.Fatalf("lastCheckpoint = %v after a reset, want %v", a.lastCheckpoint, start.Add(2*time.Minute))
+	}
+
+	// A truncated WAL is a checkpoint, but not the first write after it
+	if err := os.Truncate(path+"-wal", 0); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+	a.observeWAL(start.Add(3 * time.Minute))
+	writeWAL(3)
+	a.observeWAL(start.Add(4 * time.Minute))
+	if !a.lastCheckpoint.Equal(start.Add(3 * time.Minute)) {
+		t.Fatalf("lastCheckpoint = %v after a truncation, want %v", a.lastCheckpoint, start.Add(3*time.Minute))
+	}
+}
+
+func TestHandlerRequiresToken(t *testing.T) {
+	a := New(Config{Path: newDatabase(t), Shell: fakeShell(t, `4096\n2\n0\n0\n`, 0)})
+	server := httptest.NewServer(a.Handler("secret"))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("/healthz status = %d, want 200", resp.StatusCode)
+	}
+
+	client := &Client{HTTP: server.Client()}
+	if _, err := client.Stats(context.Background(), server.URL, "wrong"); err == nil {
+		t.Error("Stats() with the wrong token succeeded, want an error")
+	}
+	stats, err := client.Stats(context.Background(), server.URL, "secret")
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.PageCount != 2 {
+		t.Errorf("PageCount = %d, want 2", stats.PageCount)
+	}
+	if _, err := client.Command(context.Background(), server.URL, "secret", "drop"); err == nil {
+		t.Error("Command(drop) succeeded, want an error")
+	}
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	a := New(Config{Path: newDatabase(t)})
+	if _, err := a.Run(context.Background(), "drop"); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("Run(drop) error = %v, want ErrUnknownCommand", err)
+	}
+}
+
+func TestCommandsWithSqlite3(t *testing.T) {
+	shell, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.db")
+	setup := exec.Command(shell, path, "PRAGMA journal_mode = WAL; CREATE TABLE users (id INTEGER PRIMARY KEY); INSERT INTO users VALUES (1);")
+	if output, err := setup.CombinedOutput(); err != nil {
+		t.Fatalf("sqlite3 error = %v: %s", err, output)
+	}
+
+	a := New(Config{Path: path, Shell: shell, BusyTimeout: time.Second, SnapshotDir: filepath.Join(dir, "snapshots")})
+	for _, command := range []string{CommandQuickCheck, CommandCheckpoint, CommandSnapshot} {
+		result, err := a.Run(context.Background(), command)
+		if err != nil {
+			t.Fatalf("Run(%s) error = %v", command, err)
+		}
+		if !result.Succeeded {
+			t.Errorf("Run(%s) failed: %s", command, result.Output)
+		}
+	}
+	if a.lastCheckpoint == nil {
+		t.Error("lastCheckpoint = nil after a checkpoint")
+	}
+
+	snapshot := New(Config{Path: filepath.Join(dir, "snapshots", "app.db"), Shell: shell})
+	stats, err := snapshot.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats() of the snapshot error = %v", err)
+	}
+	if !reflect.DeepEqual(stats.Tables, []string{"users"}) {
+		t.Errorf("Tables of the snapshot = %v, want [users]", stats.Tables)
+	}
+}
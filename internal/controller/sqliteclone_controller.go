@@ -0,0 +1,287 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+const (
+	// CloneLabel and CloneNamespaceLabel identify the SqliteClone that created a database
+	CloneLabel          = "sqlite.io/clone"
+	CloneNamespaceLabel = "sqlite.io/clone-namespace"
+
+	clonePhaseRestoring = "Restoring"
+	clonePhaseReady     = "Ready"
+	clonePhaseFailed    = "Failed"
+)
+
+// SqliteCloneReconciler reconciles a SqliteClone object
+type SqliteCloneReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=database.sqlite.io,resources=sqliteclones,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=database.sqlite.io,resources=sqliteclones/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=database.sqlite.io,resources=sqliteclones/finalizers,verbs=update
+
+// Reconcile creates the SqliteDatabase of the clone, restored from the replica of the source
+// database, and reports its progress until it runs.
+func (r *SqliteCloneReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	clone := &databasev1alpha1.SqliteClone{}
+	if err := r.Get(ctx, req.NamespacedName, clone); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("SqliteClone resource not found. Ignoring since object must be deleted.")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get SqliteClone")
+		return ctrl.Result{}, err
+	}
+
+	// The database belongs to its users once restored
+	if clone.Status.Phase == clonePhaseReady {
+		return ctrl.Result{}, nil
+	}
+	clone.Status.ObservedGeneration = clone.Generation
+
+	targetKey := cloneTarget(clone)
+	target := &databasev1alpha1.SqliteDatabase{}
+	err := r.Get(ctx, targetKey, target)
+	switch {
+	case errors.IsNotFound(err) && clone.Status.Database != "":
+		return ctrl.Result{}, r.updateCloneStatus(ctx, clone, clonePhaseFailed, "DatabaseDeleted",
+			fmt.Sprintf("Database %s was deleted before it was restored", targetKey))
+	case errors.IsNotFound(err):
+		message, err := r.createCloneDatabase(ctx, clone)
+		if err != nil {
+			log.Error(err, "Failed to create database")
+			return ctrl.Result{}, err
+		}
+		if message != "" {
+			// The source may be created or configured later
+			if err := r.updateCloneStatus(ctx, clone, clonePhaseFailed, "InvalidSource", message); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+		}
+		clone.Status.Database = targetKey.String()
+		if err := r.updateCloneStatus(ctx, clone, clonePhaseRestoring, "Restoring",
+			fmt.Sprintf("Restoring %s from the replica of %s", targetKey, cloneSource(clone))); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	case err != nil:
+		log.Error(err, "Failed to get SqliteDatabase")
+		return ctrl.Result{}, err
+	}
+
+	// Never take over a database the clone did not create
+	if target.Labels[CloneLabel] != clone.Name || target.Labels[CloneNamespaceLabel] != clone.Namespace {
+		return ctrl.Result{}, r.updateCloneStatus(ctx, clone, clonePhaseFailed, "DatabaseExists",
+			fmt.Sprintf("SqliteDatabase %s already exists", targetKey))
+	}
+	clone.Status.Database = targetKey.String()
+
+	if target.Status.Phase == "Running" {
+		return ctrl.Result{}, r.updateCloneStatus(ctx, clone, clonePhaseReady, "Restored",
+			fmt.Sprintf("Database %s restored from the replica of %s", targetKey, cloneSource(clone)))
+	}
+	if err := r.updateCloneStatus(ctx, clone, clonePhaseRestoring, "Restoring",
+		fmt.Sprintf("Restoring %s from the replica of %s", targetKey, cloneSource(clone))); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SqliteCloneReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&databasev1alpha1.SqliteClone{}).
+		Named("sqliteclone").
+		Complete(r)
+}
+
+// cloneSource returns the namespaced name of the source database
+func cloneSource(clone *databasev1alpha1.SqliteClone) types.NamespacedName {
+	return types.NamespacedName{
+		Name:      clone.Spec.Source.Name,
+		Namespace: getDefaultString(clone.Spec.Source.Namespace, clone.Namespace),
+	}
+}
+
+// cloneTarget returns the namespaced name of the database created by the clone
+func cloneTarget(clone *databasev1alpha1.SqliteClone) types.NamespacedName {
+	return types.NamespacedName{
+		Name:      getDefaultString(clone.Spec.Target.Name, clone.Name),
+		Namespace: getDefaultString(clone.Spec.Target.Namespace, clone.Namespace),
+	}
+}
+
+// createCloneDatabase creates the database of the clone from the spec of the source database.
+// It returns a message when the source cannot be cloned.
+func (r *SqliteCloneReconciler) createCloneDatabase(ctx context.Context, clone *databasev1alpha1.SqliteClone) (string, error) {
+	sourceKey := cloneSource(clone)
+	source := &databasev1alpha1.SqliteDatabase{}
+	if err := r.Get(ctx, sourceKey, source); err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Sprintf("Source database %s not found", sourceKey), nil
+		}
+		return "", err
+	}
+	if source.Spec.Litestream == nil || !source.Spec.Litestream.Enabled || len(source.Spec.Litestream.Replicas) == 0 {
+		return fmt.Sprintf("Source database %s has no Litestream replica", sourceKey), nil
+	}
+
+	targetKey := cloneTarget(clone)
+	spec := source.Spec.DeepCopy()
+	spec.Database.Name = getDefaultString(spec.Database.Name, "database.db")
+	spec.Database.InitScript = nil
+	spec.Database.Storage.ExistingClaim = ""
+	spec.Ingress = nil
+	spec.Database.DataSource = &databasev1alpha1.DataSourceConfig{
+		Replica: &databasev1alpha1.ReplicaDataSource{
+			Replicas:  spec.Litestream.Replicas,
+			Database:  spec.Database.Name,
+			Timestamp: clone.Spec.Timestamp,
+		},
+	}
+	spec.Database.Masking = clone.Spec.Target.Masking
+	spec.Litestream = clone.Spec.Target.Litestream
+	if spec.Litestream == nil {
+		spec.Litestream = &databasev1alpha1.LitestreamConfig{Enabled: false}
+	}
+	// An ephemeral database needs a replica of its own to survive restarts, and a volume
+	// shared with the masking Job
+	if spec.Database.Storage.Type == storageTypeEphemeral &&
+		(!spec.Litestream.Enabled || len(spec.Litestream.Replicas) == 0 || len(spec.Database.Masking) > 0) {
+		spec.Database.Storage.Type = storageTypePersistent
+	}
+
+	// Secrets are namespaced, so the clone gets copies of those of the source
+	secrets := map[string]string{}
+	if targetKey.Namespace != sourceKey.Namespace {
+		rename := func(name string) string {
+			secrets[name] = fmt.Sprintf("%s-%s", targetKey.Name, name)
+			return secrets[name]
+		}
+		for i, replica := range spec.Database.DataSource.Replica.Replicas {
+			if replica.Credentials != nil {
+				spec.Database.DataSource.Replica.Replicas[i].Credentials.SecretName = rename(replica.Credentials.SecretName)
+			}
+		}
+		if spec.Database.Encryption != nil {
+			spec.Database.Encryption.SecretName = rename(spec.Database.Encryption.SecretName)
+		}
+		if spec.SqliteRest != nil && spec.SqliteRest.AuthSecret != nil {
+			spec.SqliteRest.AuthSecret = ptr.To(rename(*spec.SqliteRest.AuthSecret))
+		}
+	}
+
+	// The database outlives the clone, so it is labeled rather than owned
+	target := &databasev1alpha1.SqliteDatabase{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      targetKey.Name,
+			Namespace: targetKey.Namespace,
+			Labels: map[string]string{
+				CloneLabel:          clone.Name,
+				CloneNamespaceLabel: clone.Namespace,
+			},
+		},
+		Spec: *spec,
+	}
+	if err := r.Create(ctx, target); err != nil {
+		if errors.IsInvalid(err) {
+			return fmt.Sprintf("Unable to create database %s: %v", targetKey, err), nil
+		}
+		return "", err
+	}
+
+	for name, copyName := range secrets {
+		if err := r.copyCloneSecret(ctx, target, types.NamespacedName{Name: name, Namespace: sourceKey.Namespace}, copyName); err != nil {
+			return "", err
+		}
+	}
+
+	return "", nil
+}
+
+// copyCloneSecret copies a Secret of the source database into the namespace of the clone,
+// owned by the cloned database
+func (r *SqliteCloneReconciler) copyCloneSecret(ctx context.Context, target *databasev1alpha1.SqliteDatabase, key types.NamespacedName, name string) error {
+	source := &corev1.Secret{}
+	if err := r.Get(ctx, key, source); err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: target.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "sqlite-database",
+				"app.kubernetes.io/instance":   target.Name,
+				"app.kubernetes.io/managed-by": "sqlite-operator",
+			},
+		},
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Type = source.Type
+		secret.Data = source.Data
+		return controllerutil.SetControllerReference(target, secret, r.Scheme)
+	})
+
+	return err
+}
+
+// updateCloneStatus sets the phase of the clone and its Ready condition
+func (r *SqliteCloneReconciler) updateCloneStatus(ctx context.Context, clone *databasev1alpha1.SqliteClone, phase, reason, message string) error {
+	clone.Status.Phase = phase
+	clone.Status.Message = message
+
+	condition := metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	}
+	if phase == clonePhaseReady {
+		condition.Status = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(&clone.Status.Conditions, condition)
+
+	return r.Status().Update(ctx, clone)
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+var _ = Describe("SqliteClone Controller", func() {
+	ctx := context.Background()
+
+	sourceName := types.NamespacedName{Name: "clone-source", Namespace: "default"}
+	cloneName := types.NamespacedName{Name: "pr-42", Namespace: "default"}
+	targetName := types.NamespacedName{Name: "pr-42", Namespace: "preview"}
+
+	BeforeEach(func() {
+		By("creating the source database with a replica")
+		Expect(k8sClient.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3-credentials", Namespace: "default"},
+			StringData: map[string]string{"access-key": "AKID", "secret-key": "secret"},
+		})).To(Succeed())
+		Expect(k8sClient.Create(ctx, &databasev1alpha1.SqliteDatabase{
+			ObjectMeta: metav1.ObjectMeta{Name: sourceName.Name, Namespace: sourceName.Namespace},
+			Spec: databasev1alpha1.SqliteDatabaseSpec{
+				Database: databasev1alpha1.DatabaseConfig{
+					Name: "app.db",
+					Storage: databasev1alpha1.StorageConfig{
+						Size: "1Gi",
+					},
+				},
+				Litestream: &databasev1alpha1.LitestreamConfig{
+					Enabled: true,
+					Replicas: []databasev1alpha1.ReplicaConfig{{
+						Type:   "s3",
+						Bucket: "backups",
+						Credentials: &databasev1alpha1.CredentialsConfig{
+							SecretName: "s3-credentials",
+						},
+					}},
+				},
+			},
+		})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		source := &databasev1alpha1.SqliteDatabase{}
+		Expect(k8sClient.Get(ctx, sourceName, source)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, source)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3-credentials", Namespace: "default"},
+		})).To(Succeed())
+	})
+
+	It("should restore the replica of the source into a new database in another namespace", func() {
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: "preview"},
+		})).To(Succeed())
+
+		timestamp := metav1.NewTime(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC))
+		clone := &databasev1alpha1.SqliteClone{
+			ObjectMeta: metav1.ObjectMeta{Name: cloneName.Name, Namespace: cloneName.Namespace},
+			Spec: databasev1alpha1.SqliteCloneSpec{
+				Source:    databasev1alpha1.CloneSource{Name: sourceName.Name},
+				Timestamp: &timestamp,
+				Target:    databasev1alpha1.CloneTarget{Namespace: "preview"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, clone)).To(Succeed())
+
+		cloneReconciler := &SqliteCloneReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+		}
+		_, err := cloneReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cloneName})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("creating the database from the replica of the source without replicating it")
+		target := &databasev1alpha1.SqliteDatabase{}
+		Expect(k8sClient.Get(ctx, targetName, target)).To(Succeed())
+		Expect(target.Labels).To(HaveKeyWithValue(CloneLabel, cloneName.Name))
+		Expect(target.OwnerReferences).To(BeEmpty())
+		Expect(target.Spec.Litestream.Enabled).To(BeFalse())
+		replica := target.Spec.Database.DataSource.Replica
+		Expect(replica).NotTo(BeNil())
+		Expect(replica.Database).To(Equal("app.db"))
+		Expect(replica.Timestamp.Equal(&timestamp)).To(BeTrue())
+		Expect(replica.Replicas[0].Credentials.SecretName).To(Equal("pr-42-s3-credentials"))
+
+		By("copying the replica credentials into the namespace of the database")
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "pr-42-s3-credentials", Namespace: "preview"}, secret)).To(Succeed())
+		Expect(secret.Data).To(HaveKeyWithValue("access-key", []byte("AKID")))
+
+		Expect(k8sClient.Get(ctx, cloneName, clone)).To(Succeed())
+		Expect(clone.Status.Phase).To(Equal("Restoring"))
+		Expect(clone.Status.Database).To(Equal("preview/pr-42"))
+
+		By("restoring the source replica in an init container of the database")
+		dbReconciler := &SqliteDatabaseReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+		}
+		_, err = dbReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: targetName})
+		Expect(err).NotTo(HaveOccurred())
+
+		configMap := &corev1.ConfigMap{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "pr-42-data-source", Namespace: "preview"}, configMap)).To(Succeed())
+		Expect(configMap.Data["litestream.yml"]).To(ContainSubstring("/var/lib/sqlite/app.db"))
+
+		deployment := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, targetName, deployment)).To(Succeed())
+		restore := findContainer(deployment.Spec.Template.Spec.InitContainers, "restore-source")
+		Expect(restore).NotTo(BeNil())
+		Expect(restore.Args[0]).To(ContainSubstring("-o /data-source/app.db -timestamp 2025-03-01T12:00:00Z /var/lib/sqlite/app.db"))
+		initDB := findContainer(deployment.Spec.Template.Spec.InitContainers, "init-db")
+		Expect(initDB.Args[0]).To(ContainSubstring("cp /data-source/app.db /var/lib/sqlite/app.db.import"))
+
+		Expect(k8sClient.Delete(ctx, target)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, clone)).To(Succeed())
+	})
+
+	It("should not take over an existing database", func() {
+		clone := &databasev1alpha1.SqliteClone{
+			ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"},
+			Spec: databasev1alpha1.SqliteCloneSpec{
+				Source: databasev1alpha1.CloneSource{Name: sourceName.Name},
+				Target: databasev1alpha1.CloneTarget{Name: sourceName.Name},
+			},
+		}
+		Expect(k8sClient.Create(ctx, clone)).To(Succeed())
+
+		cloneReconciler := &SqliteCloneReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+		}
+		_, err := cloneReconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: "existing", Namespace: "default"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "existing", Namespace: "default"}, clone)).To(Succeed())
+		Expect(clone.Status.Phase).To(Equal("Failed"))
+		Expect(clone.Status.Message).To(ContainSubstring("already exists"))
+
+		Expect(k8sClient.Delete(ctx, clone)).To(Succeed())
+	})
+})
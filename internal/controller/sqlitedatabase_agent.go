@@ -0,0 +1,338 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+	"github.com/sqlite-operator/sqlite-operator/internal/agent"
+)
+
+const (
+	// AgentCommandAnnotation requests a control command from the agent: checkpoint, snapshot
+	// or quick_check. It is removed once the command ran.
+	AgentCommandAnnotation = "sqlite.io/agent-command"
+
+	// agentContainerName is the sidecar running the agent
+	agentContainerName = "sqlite-agent"
+
+	// agentPath holds the agent binary, copied from the operator image by an init container
+	agentPath = "/opt/agent"
+
+	// agentSnapshotPath receives the snapshots of the database on its volume
+	agentSnapshotPath = "/var/lib/sqlite/.snapshots"
+
+	// agentTokenKey is the key of the token in the Secret of the agent
+	agentTokenKey = "token"
+
+	// agentReadyCondition reports whether the agent answered the last poll
+	agentReadyCondition = "AgentReady"
+
+	// agentPollInterval is the interval between polls of the agent
+	agentPollInterval = time.Minute
+)
+
+// AgentClient calls the agents of the database pods, see agent.Client
+type AgentClient interface {
+	// Stats returns the statistics served by the agent at the endpoint
+	Stats(ctx context.Context, endpoint, token string) (*agent.Stats, error)
+	// Command runs a control command with the agent at the endpoint
+	Command(ctx context.Context, endpoint, token, command string) (*agent.CommandResult, error)
+}
+
+// agentEnabled returns true if the agent runs in the database pod
+func agentEnabled(sqliteDB *databasev1alpha1.SqliteDatabase) bool {
+	return sqliteDB.Spec.Agent != nil && sqliteDB.Spec.Agent.Enabled &&
+		workloadKind(sqliteDB) != workloadKindNone && !liteFSEnabled(sqliteDB)
+}
+
+// agentSecretName returns the name of the Secret holding the token of the agent
+func agentSecretName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	return fmt.Sprintf("%s-agent", sqliteDB.Name)
+}
+
+// buildAgentInstallContainer builds the init container copying the agent from the operator
+// image, so that it runs in the sqlite image next to the sqlite3 or sqlcipher shell
+func (r *SqliteDatabaseReconciler) buildAgentInstallContainer(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.Container {
+	return corev1.Container{
+		Name:            "install-agent",
+		Image:           r.resolveImages(sqliteDB).Operator,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"/agent"},
+		Args:            []string{"--install=" + agentPath + "/agent"},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "agent",
+				MountPath: agentPath,
+			},
+		},
+	}
+}
+
+// buildAgentContainer builds the sidecar running the agent
+func (r *SqliteDatabaseReconciler) buildAgentContainer(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.Container {
+	shell := "sqlite3"
+	if sqliteDB.Spec.Database.Encryption != nil {
+		shell = "sqlcipher"
+	}
+
+	container := corev1.Container{
+		Name:            agentContainerName,
+		Image:           r.resolveImages(sqliteDB).Sqlite,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{agentPath + "/agent"},
+		Args: []string{
+			fmt.Sprintf("--db=/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name),
+			"--sqlite=" + shell,
+			fmt.Sprintf("--listen=:%d", sqliteDB.Spec.Agent.Port),
+			"--snapshot-dir=" + agentSnapshotPath,
+		},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "agent",
+				ContainerPort: sqliteDB.Spec.Agent.Port,
+				Protocol:      corev1.ProtocolTCP,
+			},
+		},
+		Env: []corev1.EnvVar{
+			{
+				Name: "AGENT_TOKEN",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: agentSecretName(sqliteDB),
+						},
+						Key: agentTokenKey,
+					},
+				},
+			},
+		},
+		LivenessProbe: &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Path: "/healthz",
+					Port: intstr.FromString("agent"),
+				},
+			},
+			PeriodSeconds: 10,
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "db-storage",
+				MountPath: "/var/lib/sqlite",
+			},
+			{
+				Name:      "agent",
+				MountPath: agentPath,
+				ReadOnly:  true,
+			},
+		},
+	}
+	if sqliteDB.Spec.Agent.Resources != nil {
+		container.Resources = *sqliteDB.Spec.Agent.Resources
+	}
+	if sqliteDB.Spec.Database.Encryption != nil {
+		container.Env = append(container.Env, r.buildEncryptionKeyEnv(sqliteDB))
+	}
+
+	return container
+}
+
+// reconcileAgent creates the token of the agent, runs the control command requested with the
+// annotation and records the state reported by the agent of the ready database pod
+func (r *SqliteDatabaseReconciler) reconcileAgent(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      agentSecretName(sqliteDB),
+			Namespace: sqliteDB.Namespace,
+		},
+	}
+
+	if !agentEnabled(sqliteDB) {
+		meta.RemoveStatusCondition(&sqliteDB.Status.Conditions, agentReadyCondition)
+		sqliteDB.Status.Agent = nil
+		return r.deleteOwned(ctx, sqliteDB, secret)
+	}
+
+	// The token is generated once, rotated by deleting the Secret
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Labels = map[string]string{
+			"app.kubernetes.io/name":       "sqlite-database",
+			"app.kubernetes.io/instance":   sqliteDB.Name,
+			"app.kubernetes.io/managed-by": "sqlite-operator",
+		}
+		if len(secret.Data[agentTokenKey]) == 0 {
+			token := make([]byte, 32)
+			if _, err := rand.Read(token); err != nil {
+				return err
+			}
+			secret.Data = map[string][]byte{agentTokenKey: []byte(hex.EncodeToString(token))}
+		}
+		return controllerutil.SetControllerReference(sqliteDB, secret, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	if r.Agent == nil {
+		return nil
+	}
+	if sqliteDB.Status.Agent == nil {
+		sqliteDB.Status.Agent = &databasev1alpha1.AgentStatus{}
+	}
+	status := sqliteDB.Status.Agent
+	token := string(secret.Data[agentTokenKey])
+
+	pod, err := r.getAgentPod(ctx, sqliteDB)
+	if err != nil {
+		return err
+	}
+	if pod == nil {
+		setCondition(sqliteDB, metav1.Condition{
+			Type:               agentReadyCondition,
+			Status:             metav1.ConditionFalse,
+			LastTransitionTime: metav1.Now(),
+			Reason:             "PodNotReady",
+			Message:            "No ready database pod runs the agent",
+		})
+		return nil
+	}
+	endpoint := "http://" + net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(sqliteDB.Spec.Agent.Port)))
+
+	if command := sqliteDB.Annotations[AgentCommandAnnotation]; command != "" {
+		if err := r.runAgentCommand(ctx, sqliteDB, endpoint, token, command); err != nil {
+			return err
+		}
+	}
+
+	stats, err := r.Agent.Stats(ctx, endpoint, token)
+	if err != nil {
+		logf.FromContext(ctx).Info("Unable to poll the agent", "pod", pod.Name, "error", err.Error())
+		setCondition(sqliteDB, metav1.Condition{
+			Type:               agentReadyCondition,
+			Status:             metav1.ConditionFalse,
+			LastTransitionTime: metav1.Now(),
+			Reason:             "PollFailed",
+			Message:            err.Error(),
+		})
+		return nil
+	}
+
+	now := metav1.Now()
+	status.Pod = pod.Name
+	status.LastPoll = &now
+	status.ProbeBusyCount = stats.ProbeBusyCount
+	status.LitestreamPosition = ""
+	if stats.Litestream != nil {
+		status.LitestreamPosition = stats.Litestream.String()
+	}
+	if stats.LastCheckpoint != nil {
+		status.LastCheckpoint = &metav1.Time{Time: *stats.LastCheckpoint}
+	}
+	setCondition(sqliteDB, metav1.Condition{
+		Type:               agentReadyCondition,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: now,
+		Reason:             "Polled",
+		Message:            fmt.Sprintf("Agent of pod %s reported %d bytes in the database and %d in the WAL", pod.Name, stats.DatabaseBytes, stats.WALBytes),
+	})
+
+	return nil
+}
+
+// runAgentCommand runs the control command requested with the annotation, records its result
+// and removes the annotation. A command the agent could not be reached for is retried with
+// the next reconcile.
+func (r *SqliteDatabaseReconciler) runAgentCommand(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase, endpoint, token, command string) error {
+	log := logf.FromContext(ctx)
+
+	lastCommand := &databasev1alpha1.AgentCommandStatus{Command: command, Time: metav1.Now()}
+	switch command {
+	case agent.CommandCheckpoint, agent.CommandSnapshot, agent.CommandQuickCheck:
+		result, err := r.Agent.Command(ctx, endpoint, token, command)
+		if err != nil {
+			log.Info("Unable to run the agent command", "command", command, "error", err.Error())
+			return nil
+		}
+		lastCommand.Result = "Failed"
+		if result.Succeeded {
+			lastCommand.Result = "Succeeded"
+		}
+		lastCommand.Output = result.Output
+		if len(lastCommand.Output) > 1024 {
+			lastCommand.Output = lastCommand.Output[:1024]
+		}
+	default:
+		lastCommand.Result = "Failed"
+		lastCommand.Output = fmt.Sprintf("Unknown command %q, expected checkpoint, snapshot or quick_check", command)
+	}
+	sqliteDB.Status.Agent.LastCommand = lastCommand
+
+	if lastCommand.Result == "Succeeded" {
+		r.recordEvent(sqliteDB, corev1.EventTypeNormal, "AgentCommandSucceeded",
+			fmt.Sprintf("Agent ran %s: %s", command, lastCommand.Output))
+	} else {
+		r.recordEvent(sqliteDB, corev1.EventTypeWarning, "AgentCommandFailed",
+			fmt.Sprintf("Agent failed to run %s: %s", command, lastCommand.Output))
+	}
+
+	// Patch a copy, the response would overwrite the status recorded so far
+	patched := sqliteDB.DeepCopy()
+	delete(patched.Annotations, AgentCommandAnnotation)
+	if err := r.Patch(ctx, patched, client.MergeFrom(sqliteDB)); err != nil {
+		return err
+	}
+	sqliteDB.Annotations = patched.Annotations
+	sqliteDB.ResourceVersion = patched.ResourceVersion
+
+	return nil
+}
+
+// getAgentPod returns the ready database pod running the agent, nil if there is none. The
+// pod may run an older template without the agent until it is rolled out.
+func (r *SqliteDatabaseReconciler) getAgentPod(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (*corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(sqliteDB.Namespace), databasePodSelector(sqliteDB)); err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil || !podReady(pod) || pod.Status.PodIP == "" {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			if container.Name == agentContainerName {
+				return pod, nil
+			}
+		}
+	}
+	return nil, nil
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 	appsv1 "k8s.io/api/apps/v1"
@@ -29,16 +30,43 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
 )
 
+const (
+	// Default container images. make pin-images pins them to digests so that pods are
+	// reproducible, and make build-installer refuses to release them unpinned.
+	DefaultSqliteImage     = "keinos/sqlite3:3.46.1"
+	DefaultLitestreamImage = "litestream/litestream:0.3.13"
+	DefaultSqliteRestImage = "ghcr.io/b4fun/sqlite-rest/server:main"
+
+	// DefaultOperatorImage runs the writer lease, data source and agent install containers.
+	// The manifests set it to the image of the manager itself.
+	DefaultOperatorImage = "docker.io/stackblaze/sqlite-operator:v0.1.14"
+
+	// DefaultLiteFSImage runs LiteFS when the database is replicated by LiteFS
+	DefaultLiteFSImage = "flyio/litefs:0.5.11"
+
+	storageTypePersistent = "persistent"
+	storageTypeEphemeral  = "ephemeral"
+
+	// encryptionKeyEnv is the environment variable carrying the SQLCipher key
+	encryptionKeyEnv = "SQLITE_ENCRYPTION_KEY"
+
+	// noSqlcipherImageMessage is reported for encrypted databases when no SQLCipher image is set
+	noSqlcipherImageMessage = "encryption requires an image providing the sqlcipher CLI, set spec.images.sqlite or the --sqlcipher-image flag of the operator"
+)
+
 // LitestreamConfig represents the Litestream configuration structure
 type LitestreamConfig struct {
 	DBs []LitestreamDB `yaml:"dbs"`
@@ -57,10 +85,50 @@ type LitestreamReplica struct {
 	Endpoint               *string `yaml:"endpoint,omitempty"`
 }
 
+// Images holds the operator-wide default container images
+type Images struct {
+	Sqlite     string
+	Litestream string
+	SqliteRest string
+	Operator   string
+	LiteFS     string
+
+	// SqlcipherSqlite provides the sqlcipher CLI for encrypted databases. It has no built-in
+	// default, see Dockerfile.sqlcipher.
+	SqlcipherSqlite string
+}
+
 // SqliteDatabaseReconciler reconciles a SqliteDatabase object
 type SqliteDatabaseReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// Default images, unset entries fall back to the built-in defaults
+	Images Images
+
+	// NativeSidecars runs Litestream and the writer lease holder as init containers with
+	// restartPolicy Always, see NativeSidecarsSupported
+	NativeSidecars bool
+
+	// Executor measures the database files for autoGrow and reads the LiteFS positions,
+	// which are skipped when unset
+	Executor PodExecutor
+
+	// Recorder emits the events of the database, optional
+	Recorder record.EventRecorder
+
+	// Agent polls the agents of the database pods, which are skipped when unset
+	Agent AgentClient
+}
+
+// NativeSidecarsSupported returns true if the API server enables native sidecar
+// containers by default, which is the case from Kubernetes 1.29
+func NativeSidecarsSupported(info *version.Info) bool {
+	serverVersion, err := utilversion.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return false
+	}
+	return serverVersion.AtLeast(utilversion.MajorMinor(1, 29))
 }
 
 // +kubebuilder:rbac:groups=database.sqlite.io,resources=sqlitedatabases,verbs=get;list;watch;create;update;patch;delete
@@ -83,6 +151,7 @@ func (r *SqliteDatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	if err := r.Get(ctx, req.NamespacedName, sqliteDB); err != nil {
 		if errors.IsNotFound(err) {
 			log.Info("SqliteDatabase resource not found. Ignoring since object must be deleted.")
+			forgetDatabaseMetrics(req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "Failed to get SqliteDatabase")
@@ -95,22 +164,142 @@ func (r *SqliteDatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	// Update status with observed generation
 	if sqliteDB.Status.ObservedGeneration != sqliteDB.Generation {
 		sqliteDB.Status.ObservedGeneration = sqliteDB.Generation
-		if err := r.Status().Update(ctx, sqliteDB); err != nil {
+		if err := r.writeStatus(ctx, sqliteDB); err != nil {
 			log.Error(err, "Failed to update observed generation")
+			recordReconcileError(sqliteDB, "status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Encrypted databases need an image providing the sqlcipher CLI
+	if sqliteDB.Spec.Database.Encryption != nil && r.resolveImages(sqliteDB).Sqlite == "" {
+		sqliteDB.Status.Phase = "Failed"
+		sqliteDB.Status.Message = noSqlcipherImageMessage
+		if err := r.writeStatus(ctx, sqliteDB); err != nil {
+			log.Error(err, "Failed to update status")
+			recordReconcileError(sqliteDB, "status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// The writer lease is held by a native sidecar from before the first init container opens
+	// the database
+	if writerLeaseEnabled(sqliteDB) && !r.NativeSidecars {
+		sqliteDB.Status.Phase = "Failed"
+		sqliteDB.Status.Message = noNativeSidecarsMessage
+		if err := r.writeStatus(ctx, sqliteDB); err != nil {
+			log.Error(err, "Failed to update status")
+			recordReconcileError(sqliteDB, "status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// LiteFS replicates the database between the pods of its own StatefulSet
+	if liteFSEnabled(sqliteDB) {
+		if err := r.reconcileLiteFS(ctx, sqliteDB); err != nil {
+			log.Error(err, "Failed to reconcile LiteFS")
+			recordReconcileError(sqliteDB, "litefs")
 			return ctrl.Result{}, err
 		}
+		if err := r.updateStatus(ctx, sqliteDB); err != nil {
+			log.Error(err, "Failed to update status")
+			recordReconcileError(sqliteDB, "status")
+			return ctrl.Result{}, err
+		}
+
+		// Follow the election of the primary and the replication positions
+		return ctrl.Result{RequeueAfter: liteFSStatusInterval}, nil
 	}
 
-	// Create/Update PVC
-	if err := r.reconcilePVC(ctx, sqliteDB); err != nil {
-		log.Error(err, "Failed to reconcile PVC")
+	// Move the database volume over when the workload kind changed
+	migrating, err := r.reconcileWorkloadMigration(ctx, sqliteDB)
+	if err != nil {
+		log.Error(err, "Failed to migrate workload")
+		recordReconcileError(sqliteDB, "workload-migration")
 		return ctrl.Result{}, err
 	}
+	if migrating {
+		sqliteDB.Status.Phase = "Pending"
+		sqliteDB.Status.Message = fmt.Sprintf("Moving database volume to the %s", workloadKind(sqliteDB))
+		if err := r.writeStatus(ctx, sqliteDB); err != nil {
+			log.Error(err, "Failed to update status")
+			recordReconcileError(sqliteDB, "status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+
+	// Copy the database to a new claim when the StorageClass changed
+	migrating, err = r.reconcileStorageClassMigration(ctx, sqliteDB)
+	if err != nil {
+		log.Error(err, "Failed to migrate StorageClass")
+		recordReconcileError(sqliteDB, "storage-class-migration")
+		return ctrl.Result{}, err
+	}
+	if migrating {
+		sqliteDB.Status.Phase = "Pending"
+		sqliteDB.Status.Message = fmt.Sprintf("Copying database to StorageClass %q",
+			getStringValue(sqliteDB.Spec.Database.Storage.StorageClass, ""))
+		if err := r.writeStatus(ctx, sqliteDB); err != nil {
+			log.Error(err, "Failed to update status")
+			recordReconcileError(sqliteDB, "status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+
+	// Create/Update PVC, the StatefulSet creates its own from the volumeClaimTemplate
+	if sqliteDB.Spec.Database.Storage.ExistingClaim != "" {
+		// Use the existing claim once no other pod writes to it
+		message, err := r.checkExistingClaim(ctx, sqliteDB)
+		if err != nil {
+			log.Error(err, "Failed to check existing claim")
+			recordReconcileError(sqliteDB, "existing-claim")
+			return ctrl.Result{}, err
+		}
+		if message != "" {
+			sqliteDB.Status.Phase = "Failed"
+			sqliteDB.Status.Message = message
+			if err := r.writeStatus(ctx, sqliteDB); err != nil {
+				log.Error(err, "Failed to update status")
+				recordReconcileError(sqliteDB, "status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+		}
+	} else if workloadKind(sqliteDB) != workloadKindStatefulSet && !ephemeralStorage(sqliteDB) {
+		if err := r.reconcilePVC(ctx, sqliteDB); err != nil {
+			log.Error(err, "Failed to reconcile PVC")
+			recordReconcileError(sqliteDB, "pvc")
+			return ctrl.Result{}, err
+		}
+	}
 
 	// Create/Update Litestream ConfigMap if enabled
-	if sqliteDB.Spec.Litestream != nil && sqliteDB.Spec.Litestream.Enabled {
+	if litestreamReplicating(sqliteDB) {
 		if err := r.reconcileLitestreamConfig(ctx, sqliteDB); err != nil {
 			log.Error(err, "Failed to reconcile Litestream ConfigMap")
+			recordReconcileError(sqliteDB, "litestream-config")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Create/Update the Litestream ConfigMap of the source database if restoring its replica
+	if sqliteDB.Spec.Database.DataSource != nil && sqliteDB.Spec.Database.DataSource.Replica != nil {
+		if err := r.reconcileDataSourceConfig(ctx, sqliteDB); err != nil {
+			log.Error(err, "Failed to reconcile data source ConfigMap")
+			recordReconcileError(sqliteDB, "data-source-config")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Create/Update the Litestream ConfigMap of the source of a standby
+	if sqliteDB.Spec.Standby != nil {
+		if err := r.reconcileStandbyConfig(ctx, sqliteDB); err != nil {
+			log.Error(err, "Failed to reconcile standby ConfigMap")
+			recordReconcileError(sqliteDB, "standby-config")
 			return ctrl.Result{}, err
 		}
 	}
@@ -119,13 +308,70 @@ func (r *SqliteDatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	if sqliteDB.Spec.SqliteRest != nil && sqliteDB.Spec.SqliteRest.Enabled {
 		if err := r.reconcileSqliteRestConfig(ctx, sqliteDB); err != nil {
 			log.Error(err, "Failed to reconcile sqlite-rest ConfigMap")
+			recordReconcileError(sqliteDB, "sqlite-rest-config")
 			return ctrl.Result{}, err
 		}
 	}
 
-	// Create/Update Deployment
-	if err := r.reconcileDeployment(ctx, sqliteDB); err != nil {
-		log.Error(err, "Failed to reconcile Deployment")
+	// Create/Update writer lease and its RBAC if enabled
+	if writerLeaseEnabled(sqliteDB) {
+		if err := r.reconcileWriterLease(ctx, sqliteDB); err != nil {
+			log.Error(err, "Failed to reconcile writer lease")
+			recordReconcileError(sqliteDB, "writer-lease")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Import and mask the data source before the database is first served
+	masking, err := r.reconcileMasking(ctx, sqliteDB)
+	if err != nil {
+		log.Error(err, "Failed to reconcile masking")
+		recordReconcileError(sqliteDB, "masking")
+		return ctrl.Result{}, err
+	}
+	if masking {
+		sqliteDB.Status.Phase = "Pending"
+		sqliteDB.Status.Message = "Masking data source"
+		if err := r.writeStatus(ctx, sqliteDB); err != nil {
+			log.Error(err, "Failed to update status")
+			recordReconcileError(sqliteDB, "status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+
+	// Create/Update the workload, with None the pod is injected into the application pods
+	switch workloadKind(sqliteDB) {
+	case workloadKindStatefulSet:
+		if err := r.reconcileHeadlessService(ctx, sqliteDB); err != nil {
+			log.Error(err, "Failed to reconcile headless Service")
+			recordReconcileError(sqliteDB, "headless-service")
+			return ctrl.Result{}, err
+		}
+		if err := r.reconcileStatefulSet(ctx, sqliteDB); err != nil {
+			log.Error(err, "Failed to reconcile StatefulSet")
+			recordReconcileError(sqliteDB, "statefulset")
+			return ctrl.Result{}, err
+		}
+	case workloadKindDeployment:
+		if err := r.reconcileDeployment(ctx, sqliteDB); err != nil {
+			log.Error(err, "Failed to reconcile Deployment")
+			recordReconcileError(sqliteDB, "deployment")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Grow the database volume as the database approaches its capacity
+	if err := r.reconcileAutoGrow(ctx, sqliteDB); err != nil {
+		log.Error(err, "Failed to reconcile storage auto-grow")
+		recordReconcileError(sqliteDB, "auto-grow")
+		return ctrl.Result{}, err
+	}
+
+	// Expand the database volume when its size is increased
+	if err := r.reconcileStorageSize(ctx, sqliteDB); err != nil {
+		log.Error(err, "Failed to reconcile storage size")
+		recordReconcileError(sqliteDB, "storage-size")
 		return ctrl.Result{}, err
 	}
 
@@ -133,6 +379,7 @@ func (r *SqliteDatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	if sqliteDB.Spec.SqliteRest != nil && sqliteDB.Spec.SqliteRest.Enabled {
 		if err := r.reconcileService(ctx, sqliteDB); err != nil {
 			log.Error(err, "Failed to reconcile Service")
+			recordReconcileError(sqliteDB, "service")
 			return ctrl.Result{}, err
 		}
 	}
@@ -141,27 +388,109 @@ func (r *SqliteDatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	if sqliteDB.Spec.Ingress != nil && sqliteDB.Spec.Ingress.Enabled {
 		if err := r.reconcileIngress(ctx, sqliteDB); err != nil {
 			log.Error(err, "Failed to reconcile Ingress")
+			recordReconcileError(sqliteDB, "ingress")
 			return ctrl.Result{}, err
 		}
 	}
 
+	// Create/Update/Delete the read replicas following the Litestream replica
+	if err := r.reconcileReadReplicas(ctx, sqliteDB); err != nil {
+		log.Error(err, "Failed to reconcile read replicas")
+		recordReconcileError(sqliteDB, "read-replicas")
+		return ctrl.Result{}, err
+	}
+
+	// Create/Update/Delete the CronJob verifying the Litestream replica
+	if err := r.reconcileBackupVerification(ctx, sqliteDB); err != nil {
+		log.Error(err, "Failed to reconcile backup verification")
+		recordReconcileError(sqliteDB, "backup-verification")
+		return ctrl.Result{}, err
+	}
+
+	// Record the results of the maintenance tasks and start the tasks due
+	maintenanceAfter, err := r.reconcileMaintenance(ctx, sqliteDB)
+	if err != nil {
+		log.Error(err, "Failed to reconcile maintenance")
+		recordReconcileError(sqliteDB, "maintenance")
+		return ctrl.Result{}, err
+	}
+
+	// Record the restores of corrupt databases and restart a pod failing its integrity check
+	if err := r.reconcileRecovery(ctx, sqliteDB); err != nil {
+		log.Error(err, "Failed to reconcile recovery")
+		recordReconcileError(sqliteDB, "recovery")
+		return ctrl.Result{}, err
+	}
+
+	// Run the requested agent command and record the state reported by the agent
+	if err := r.reconcileAgent(ctx, sqliteDB); err != nil {
+		log.Error(err, "Failed to reconcile agent")
+		recordReconcileError(sqliteDB, "agent")
+		return ctrl.Result{}, err
+	}
+
+	// Refresh the statistics of the database and the last backup
+	statisticsAfter, err := r.reconcileStatistics(ctx, sqliteDB)
+	if err != nil {
+		log.Error(err, "Failed to reconcile statistics")
+		recordReconcileError(sqliteDB, "statistics")
+		return ctrl.Result{}, err
+	}
+
 	// Update status
 	if err := r.updateStatus(ctx, sqliteDB); err != nil {
 		log.Error(err, "Failed to update status")
+		recordReconcileError(sqliteDB, "status")
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	// Run the next maintenance task, poll the agent and refresh the statistics on time
+	requeueAfter := earliest(maintenanceAfter, statisticsAfter)
+	if agentEnabled(sqliteDB) && r.Agent != nil {
+		requeueAfter = earliest(agentPollInterval, requeueAfter)
+	}
+
+	// Measure the lag of a standby and notice its promotion
+	if sqliteDB.Spec.Standby != nil && !standbyPromoted(sqliteDB) {
+		return ctrl.Result{RequeueAfter: earliest(standbyStatusInterval, requeueAfter)}, nil
+	}
+
+	// Measure the database files again later
+	if autoGrowEnabled(sqliteDB) {
+		return ctrl.Result{RequeueAfter: earliest(autoGrowInterval, requeueAfter)}, nil
+	}
+
+	// Report the result of the verification Jobs run by the CronJob
+	if backupVerificationEnabled(sqliteDB) {
+		return ctrl.Result{RequeueAfter: earliest(backupVerificationInterval, requeueAfter)}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SqliteDatabaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Export the state of the databases read from the cache at each scrape
+	if err := metrics.Registry.Register(newDatabaseCollector(mgr.GetClient())); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&databasev1alpha1.SqliteDatabase{}).
 		Named("sqlitedatabase").
 		Complete(r)
 }
 
+// writeStatus updates the status of the database. The response replaces the whole object,
+// spec included, so the defaults are applied again for the rest of the reconcile.
+func (r *SqliteDatabaseReconciler) writeStatus(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	if err := r.Status().Update(ctx, sqliteDB); err != nil {
+		return err
+	}
+	r.setDefaults(sqliteDB)
+	return nil
+}
+
 // setDefaults sets default values for the SqliteDatabase
 func (r *SqliteDatabaseReconciler) setDefaults(sqliteDB *databasev1alpha1.SqliteDatabase) {
 	// Set default database name if not specified
@@ -180,6 +509,9 @@ func (r *SqliteDatabaseReconciler) setDefaults(sqliteDB *databasev1alpha1.Sqlite
 			Enabled: true,
 		}
 	}
+	if sqliteDB.Spec.Litestream.Mode == "" {
+		sqliteDB.Spec.Litestream.Mode = litestreamModeSidecar
+	}
 
 	// Set default sqlite-rest disabled if not specified (sidecar mode)
 	if sqliteDB.Spec.SqliteRest == nil {
@@ -193,9 +525,103 @@ func (r *SqliteDatabaseReconciler) setDefaults(sqliteDB *databasev1alpha1.Sqlite
 		}
 	}
 
-	// Set default access mode to ReadWriteMany for sidecar mode
+	// Set default storage type if not specified
+	if sqliteDB.Spec.Database.Storage.Type == "" {
+		sqliteDB.Spec.Database.Storage.Type = storageTypePersistent
+	}
+
+	// Set default replication mode and LiteFS pods if not specified
+	if sqliteDB.Spec.Replication == nil {
+		sqliteDB.Spec.Replication = &databasev1alpha1.ReplicationConfig{}
+	}
+	if sqliteDB.Spec.Replication.Mode == "" {
+		sqliteDB.Spec.Replication.Mode = replicationModeLitestream
+	}
+	if liteFSEnabled(sqliteDB) {
+		if sqliteDB.Spec.Replication.LiteFS == nil {
+			sqliteDB.Spec.Replication.LiteFS = &databasev1alpha1.LiteFSConfig{}
+		}
+		setLiteFSDefaults(sqliteDB.Spec.Replication.LiteFS)
+	}
+
+	// Set default access mode to ReadWriteMany for sidecar mode, each LiteFS pod has its own volume
 	if sqliteDB.Spec.Database.Storage.AccessMode == "" {
 		sqliteDB.Spec.Database.Storage.AccessMode = "ReadWriteMany"
+		if liteFSEnabled(sqliteDB) {
+			sqliteDB.Spec.Database.Storage.AccessMode = "ReadWriteOnce"
+		}
+	}
+
+	// Set default encryption key field if encryption is enabled
+	if sqliteDB.Spec.Database.Encryption != nil && sqliteDB.Spec.Database.Encryption.KeyField == nil {
+		keyField := "key"
+		sqliteDB.Spec.Database.Encryption.KeyField = &keyField
+	}
+
+	// Set default writer lease timings if enabled, the lease is opt-in
+	if writerLease := sqliteDB.Spec.WriterLease; writerLease != nil {
+		if writerLease.LeaseDurationSeconds == 0 {
+			writerLease.LeaseDurationSeconds = 15
+		}
+		if writerLease.RenewDeadlineSeconds == 0 {
+			writerLease.RenewDeadlineSeconds = 10
+		}
+	}
+
+	// Set default autoGrow threshold and increment if enabled
+	if autoGrow := sqliteDB.Spec.Database.Storage.AutoGrow; autoGrow != nil {
+		if autoGrow.ThresholdPercent == 0 {
+			autoGrow.ThresholdPercent = 80
+		}
+		if autoGrow.Increment == "" {
+			autoGrow.Increment = "1Gi"
+		}
+	}
+
+	// Set default read replica sync interval if enabled
+	if sqliteDB.Spec.ReadReplicas != nil && sqliteDB.Spec.ReadReplicas.SyncIntervalSeconds == 0 {
+		sqliteDB.Spec.ReadReplicas.SyncIntervalSeconds = 10
+	}
+
+	// Set default standby sync interval
+	if sqliteDB.Spec.Standby != nil && sqliteDB.Spec.Standby.SyncIntervalSeconds == 0 {
+		sqliteDB.Spec.Standby.SyncIntervalSeconds = 10
+	}
+
+	// Set default time the maintenance tasks wait for the locks of the writer
+	if sqliteDB.Spec.Maintenance != nil && sqliteDB.Spec.Maintenance.BusyTimeoutSeconds == 0 {
+		sqliteDB.Spec.Maintenance.BusyTimeoutSeconds = 60
+	}
+
+	// Set default recovery check and quarantine if enabled
+	if recovery := sqliteDB.Spec.Recovery; recovery != nil {
+		if recovery.IntegrityCheck == "" {
+			recovery.IntegrityCheck = "quick"
+		}
+		if recovery.KeepQuarantined == 0 {
+			recovery.KeepQuarantined = 1
+		}
+	}
+
+	// Set default agent port if enabled
+	if sqliteDB.Spec.Agent != nil && sqliteDB.Spec.Agent.Port == 0 {
+		sqliteDB.Spec.Agent.Port = 9090
+	}
+
+	// Set default refresh of the statistics of the database
+	if sqliteDB.Spec.Statistics == nil {
+		sqliteDB.Spec.Statistics = &databasev1alpha1.StatisticsConfig{}
+	}
+	if sqliteDB.Spec.Statistics.IntervalSeconds == 0 {
+		sqliteDB.Spec.Statistics.IntervalSeconds = 300
+	}
+
+	// Set default workload kind if not specified
+	if sqliteDB.Spec.Workload == nil {
+		sqliteDB.Spec.Workload = &databasev1alpha1.WorkloadConfig{}
+	}
+	if sqliteDB.Spec.Workload.Kind == "" {
+		sqliteDB.Spec.Workload.Kind = workloadKindDeployment
 	}
 
 	// Set default Ingress disabled if not specified
@@ -208,6 +634,46 @@ func (r *SqliteDatabaseReconciler) setDefaults(sqliteDB *databasev1alpha1.Sqlite
 
 // reconcilePVC creates or updates the PersistentVolumeClaim
 func (r *SqliteDatabaseReconciler) reconcilePVC(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deploymentClaimName(sqliteDB),
+			Namespace: sqliteDB.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "sqlite-database",
+				"app.kubernetes.io/instance":   sqliteDB.Name,
+				"app.kubernetes.io/managed-by": "sqlite-operator",
+			},
+		},
+		Spec: r.buildPVCSpec(sqliteDB),
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, pvc, func() error {
+		// Set owner reference
+		return controllerutil.SetControllerReference(sqliteDB, pvc, r.Scheme)
+	})
+
+	return err
+}
+
+// ephemeralStorage returns true if the database lives in an emptyDir restored from the replica
+func ephemeralStorage(sqliteDB *databasev1alpha1.SqliteDatabase) bool {
+	return sqliteDB.Spec.Database.Storage.Type == storageTypeEphemeral
+}
+
+// restoreIfEmptyFlags returns the flags of litestream restore restoring the database when the
+// volume is empty. An ephemeral database is always empty when it starts, so it only starts
+// without a replica when allowEmpty is set, rather than replacing a replica that cannot be
+// found with an empty database.
+func restoreIfEmptyFlags(sqliteDB *databasev1alpha1.SqliteDatabase) []string {
+	flags := []string{"-if-db-not-exists"}
+	if !ephemeralStorage(sqliteDB) || sqliteDB.Spec.Database.Storage.AllowEmpty {
+		flags = append(flags, "-if-replica-exists")
+	}
+	return flags
+}
+
+// buildPVCSpec builds the spec of the database PersistentVolumeClaim
+func (r *SqliteDatabaseReconciler) buildPVCSpec(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.PersistentVolumeClaimSpec {
 	// Convert string to access mode
 	accessMode := corev1.ReadWriteOnce
 	switch sqliteDB.Spec.Database.Storage.AccessMode {
@@ -219,36 +685,20 @@ func (r *SqliteDatabaseReconciler) reconcilePVC(ctx context.Context, sqliteDB *d
 		accessMode = corev1.ReadWriteOnce
 	}
 
-	pvc := &corev1.PersistentVolumeClaim{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%s-db-storage", sqliteDB.Name),
-			Namespace: sqliteDB.Namespace,
-			Labels: map[string]string{
-				"app.kubernetes.io/name":       "sqlite-database",
-				"app.kubernetes.io/instance":   sqliteDB.Name,
-				"app.kubernetes.io/managed-by": "sqlite-operator",
-			},
-		},
-		Spec: corev1.PersistentVolumeClaimSpec{
-			AccessModes: []corev1.PersistentVolumeAccessMode{accessMode},
-			Resources: corev1.VolumeResourceRequirements{
-				Requests: corev1.ResourceList{
-					corev1.ResourceStorage: resource.MustParse(sqliteDB.Spec.Database.Storage.Size),
-				},
+	spec := corev1.PersistentVolumeClaimSpec{
+		AccessModes: []corev1.PersistentVolumeAccessMode{accessMode},
+		Resources: corev1.VolumeResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceStorage: resource.MustParse(sqliteDB.Spec.Database.Storage.Size),
 			},
 		},
 	}
 
 	if sqliteDB.Spec.Database.Storage.StorageClass != nil {
-		pvc.Spec.StorageClassName = sqliteDB.Spec.Database.Storage.StorageClass
+		spec.StorageClassName = sqliteDB.Spec.Database.Storage.StorageClass
 	}
 
-	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, pvc, func() error {
-		// Set owner reference
-		return controllerutil.SetControllerReference(sqliteDB, pvc, r.Scheme)
-	})
-
-	return err
+	return spec
 }
 
 // reconcileLitestreamConfig creates or updates the Litestream ConfigMap
@@ -399,94 +849,184 @@ func (r *SqliteDatabaseReconciler) reconcileDeployment(ctx context.Context, sqli
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      sqliteDB.Name,
 			Namespace: sqliteDB.Namespace,
-			Labels: map[string]string{
-				"app.kubernetes.io/name":       "sqlite-database",
-				"app.kubernetes.io/instance":   sqliteDB.Name,
-				"app.kubernetes.io/managed-by": "sqlite-operator",
-			},
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: int32Ptr(1),
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app.kubernetes.io/name":     "sqlite-database",
-					"app.kubernetes.io/instance": sqliteDB.Name,
-				},
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app.kubernetes.io/name":     "sqlite-database",
-						"app.kubernetes.io/instance": sqliteDB.Name,
-					},
-				},
-				Spec: corev1.PodSpec{
-					InitContainers: r.buildInitContainers(sqliteDB),
-					Containers:     r.buildContainers(sqliteDB),
-					Volumes:        r.buildVolumes(sqliteDB),
-				},
-			},
 		},
 	}
 
 	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, deployment, func() error {
+		deployment.Labels = map[string]string{
+			"app.kubernetes.io/name":       "sqlite-database",
+			"app.kubernetes.io/instance":   sqliteDB.Name,
+			"app.kubernetes.io/managed-by": "sqlite-operator",
+		}
+		deployment.Spec.Replicas = int32Ptr(1)
+		// Recreate ensures the old pod is gone before a new one starts writing
+		deployment.Spec.Strategy = appsv1.DeploymentStrategy{
+			Type: appsv1.RecreateDeploymentStrategyType,
+		}
+		deployment.Spec.Selector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"app.kubernetes.io/name":     "sqlite-database",
+				"app.kubernetes.io/instance": sqliteDB.Name,
+			},
+		}
+		deployment.Spec.Template = r.buildPodTemplate(sqliteDB)
+
 		return controllerutil.SetControllerReference(sqliteDB, deployment, r.Scheme)
 	})
 
 	return err
 }
 
+// buildPodTemplate builds the pod template of the database workload
+func (r *SqliteDatabaseReconciler) buildPodTemplate(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.PodTemplateSpec {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				"app.kubernetes.io/name":     "sqlite-database",
+				"app.kubernetes.io/instance": sqliteDB.Name,
+			},
+		},
+		Spec: corev1.PodSpec{
+			InitContainers:   r.buildInitContainers(sqliteDB),
+			Containers:       r.buildContainers(sqliteDB),
+			Volumes:          r.buildVolumes(sqliteDB),
+			ImagePullSecrets: sqliteDB.Spec.ImagePullSecrets,
+		},
+	}
+	if writerLeaseEnabled(sqliteDB) {
+		template.Spec.ServiceAccountName = serviceAccountName(sqliteDB)
+	}
+	applyStorageSubPath(sqliteDB, &template.Spec)
+	r.applyPodTemplate(sqliteDB, &template)
+
+	return template
+}
+
+// applyPodTemplate merges the user pod template overrides onto the generated pod template
+func (r *SqliteDatabaseReconciler) applyPodTemplate(sqliteDB *databasev1alpha1.SqliteDatabase, template *corev1.PodTemplateSpec) {
+	overrides := sqliteDB.Spec.PodTemplate
+	if overrides == nil {
+		return
+	}
+
+	// Generated labels take precedence so the selector keeps matching
+	for key, value := range overrides.Labels {
+		if _, exists := template.Labels[key]; !exists {
+			template.Labels[key] = value
+		}
+	}
+	if len(overrides.Annotations) > 0 {
+		if template.Annotations == nil {
+			template.Annotations = make(map[string]string)
+		}
+		for key, value := range overrides.Annotations {
+			template.Annotations[key] = value
+		}
+	}
+
+	podSpec := &template.Spec
+	podSpec.NodeSelector = overrides.NodeSelector
+	podSpec.Tolerations = overrides.Tolerations
+	podSpec.Affinity = overrides.Affinity
+	podSpec.TopologySpreadConstraints = overrides.TopologySpreadConstraints
+	podSpec.PriorityClassName = overrides.PriorityClassName
+	podSpec.SecurityContext = overrides.SecurityContext
+	podSpec.TerminationGracePeriodSeconds = overrides.TerminationGracePeriodSeconds
+
+	if overrides.ContainerSecurityContext != nil {
+		for i := range podSpec.InitContainers {
+			podSpec.InitContainers[i].SecurityContext = overrides.ContainerSecurityContext.DeepCopy()
+		}
+		for i := range podSpec.Containers {
+			podSpec.Containers[i].SecurityContext = overrides.ContainerSecurityContext.DeepCopy()
+		}
+	}
+}
+
+// resolveImages returns the images for the database pod, applying per-database overrides
+// on top of the operator defaults
+func (r *SqliteDatabaseReconciler) resolveImages(sqliteDB *databasev1alpha1.SqliteDatabase) databasev1alpha1.ImagesStatus {
+	images := databasev1alpha1.ImagesStatus{
+		Sqlite:     getDefaultString(r.Images.Sqlite, DefaultSqliteImage),
+		Litestream: getDefaultString(r.Images.Litestream, DefaultLitestreamImage),
+		SqliteRest: getDefaultString(r.Images.SqliteRest, DefaultSqliteRestImage),
+		Operator:   getDefaultString(r.Images.Operator, DefaultOperatorImage),
+		LiteFS:     getDefaultString(r.Images.LiteFS, DefaultLiteFSImage),
+	}
+
+	// Swap to the SQLCipher image if encryption is enabled, empty when none is configured
+	if sqliteDB.Spec.Database.Encryption != nil {
+		images.Sqlite = r.Images.SqlcipherSqlite
+	}
+
+	if overrides := sqliteDB.Spec.Images; overrides != nil {
+		images.Sqlite = getStringValue(overrides.Sqlite, images.Sqlite)
+		images.Litestream = getStringValue(overrides.Litestream, images.Litestream)
+		images.SqliteRest = getStringValue(overrides.SqliteRest, images.SqliteRest)
+		images.LiteFS = getStringValue(overrides.LiteFS, images.LiteFS)
+	}
+
+	return images
+}
+
 // buildInitContainers builds the init container specifications
 func (r *SqliteDatabaseReconciler) buildInitContainers(sqliteDB *databasev1alpha1.SqliteDatabase) []corev1.Container {
-	initContainers := []corev1.Container{
-		{
-			Name:    "init-db",
-			Image:   "keinos/sqlite3:latest",
-			Command: []string{"/bin/sh", "-c"},
-			Args: []string{fmt.Sprintf(`
-				set -e
-				mkdir -p /var/lib/sqlite
-				if [ ! -f /var/lib/sqlite/%s ]; then
-					echo "Creating empty database..."
-					sqlite3 /var/lib/sqlite/%s "SELECT 1;"
-					echo "Database created at /var/lib/sqlite/%s"
-				else
-					echo "Database already exists"
-				fi`, sqliteDB.Spec.Database.Name, sqliteDB.Spec.Database.Name, sqliteDB.Spec.Database.Name)},
-			VolumeMounts: []corev1.VolumeMount{
-				{
-					Name:      "db-storage",
-					MountPath: "/var/lib/sqlite",
-				},
+	initContainer := corev1.Container{
+		Name:            "init-db",
+		Image:           r.resolveImages(sqliteDB).Sqlite,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"/bin/sh", "-c"},
+		Args:            []string{r.buildSqliteInitScript(sqliteDB)},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "db-storage",
+				MountPath: "/var/lib/sqlite",
 			},
 		},
 	}
 
 	// Optionally add init script volume mount if configured
 	if sqliteDB.Spec.Database.InitScript != nil {
-		initContainers[0].VolumeMounts = append(initContainers[0].VolumeMounts, corev1.VolumeMount{
+		initContainer.VolumeMounts = append(initContainer.VolumeMounts, corev1.VolumeMount{
 			Name:      "init-script",
 			MountPath: "/init",
 		})
-		initContainers[0].Args[0] = r.buildSqliteInitScript(sqliteDB)
 	}
 
-	return initContainers
-}
+	// Pass the key to SQLCipher if encryption is enabled
+	if sqliteDB.Spec.Database.Encryption != nil {
+		initContainer.Env = append(initContainer.Env, r.buildEncryptionKeyEnv(sqliteDB))
+	}
 
-// buildContainers builds the container specifications
-func (r *SqliteDatabaseReconciler) buildContainers(sqliteDB *databasev1alpha1.SqliteDatabase) []corev1.Container {
-	containers := []corev1.Container{}
+	initContainers := []corev1.Container{initContainer}
 
-	// Note: SQLite is now handled by init container for sidecar mode
+	// Import the data source, downloaded first when it is a URL
+	if importsDataSource(sqliteDB) {
+		initContainers[0].VolumeMounts = append(initContainers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      "data-source",
+			MountPath: dataSourcePath,
+			ReadOnly:  true,
+		})
+		switch {
+		case sqliteDB.Spec.Database.DataSource.URL != nil:
+			initContainers = append([]corev1.Container{r.buildFetchContainer(sqliteDB)}, initContainers...)
+		case sqliteDB.Spec.Database.DataSource.Replica != nil:
+			initContainers = append([]corev1.Container{r.buildReplicaRestoreContainer(sqliteDB)}, initContainers...)
+		}
+	}
 
-	// Litestream container if enabled
-	if sqliteDB.Spec.Litestream != nil && sqliteDB.Spec.Litestream.Enabled {
-		litestreamContainer := corev1.Container{
-			Name:    "litestream",
-			Image:   "litestream/litestream:latest",
-			Command: []string{"litestream"},
-			Args:    []string{"replicate", "-config", "/etc/litestream/litestream.yml"},
+	// Restore the database from its replica when the volume is empty
+	if litestreamReplicating(sqliteDB) && len(sqliteDB.Spec.Litestream.Replicas) > 0 {
+		restoreContainer := corev1.Container{
+			Name:            "restore-db",
+			Image:           r.resolveImages(sqliteDB).Litestream,
+			ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+			Command:         []string{"litestream"},
+			Args: append(append([]string{"restore"}, restoreIfEmptyFlags(sqliteDB)...),
+				"-config", "/etc/litestream/litestream.yml",
+				fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name),
+			),
+			Env: r.buildLitestreamEnv(sqliteDB),
 			VolumeMounts: []corev1.VolumeMount{
 				{
 					Name:      "db-storage",
@@ -499,46 +1039,76 @@ func (r *SqliteDatabaseReconciler) buildContainers(sqliteDB *databasev1alpha1.Sq
 			},
 		}
 
-		// Add environment variables for credentials
-		for _, replica := range sqliteDB.Spec.Litestream.Replicas {
-			if replica.Credentials != nil {
-				litestreamContainer.Env = append(litestreamContainer.Env, []corev1.EnvVar{
-					{
-						Name: "LITESTREAM_ACCESS_KEY_ID",
-						ValueFrom: &corev1.EnvVarSource{
-							SecretKeyRef: &corev1.SecretKeySelector{
-								LocalObjectReference: corev1.LocalObjectReference{
-									Name: replica.Credentials.SecretName,
-								},
-								Key: getStringValue(replica.Credentials.AccessKeyField, "access-key"),
-							},
-						},
-					},
-					{
-						Name: "LITESTREAM_SECRET_ACCESS_KEY",
-						ValueFrom: &corev1.EnvVarSource{
-							SecretKeyRef: &corev1.SecretKeySelector{
-								LocalObjectReference: corev1.LocalObjectReference{
-									Name: replica.Credentials.SecretName,
-								},
-								Key: getStringValue(replica.Credentials.SecretKeyField, "secret-key"),
-							},
-						},
-					},
-				}...)
-			}
+		// Check the database first, and restore the replica in place of a corrupt one
+		if autoRestoreEnabled(sqliteDB) {
+			restoreContainer.Command = []string{"/bin/sh", "-c"}
+			restoreContainer.Args = []string{buildRecoveryRestoreScript(sqliteDB)}
+			initContainers = append([]corev1.Container{r.buildCheckContainer(sqliteDB), restoreContainer}, initContainers...)
+		} else {
+			initContainers = append([]corev1.Container{restoreContainer}, initContainers...)
 		}
+	}
+
+	// Restore the replica of the source of a standby, or once more when it is promoted
+	if sqliteDB.Spec.Standby != nil && !standbyPromoted(sqliteDB) {
+		initContainers = append([]corev1.Container{r.buildStandbyInitContainer(sqliteDB)}, initContainers...)
+	}
 
-		containers = append(containers, litestreamContainer)
+	// Hold the writer lease before anything opens the database
+	if writerLeaseEnabled(sqliteDB) {
+		initContainers = append([]corev1.Container{r.buildWriterLeaseSidecar(sqliteDB)}, initContainers...)
+	}
+
+	// Copy the agent into a volume shared with its sidecar
+	if agentEnabled(sqliteDB) {
+		initContainers = append(initContainers, r.buildAgentInstallContainer(sqliteDB))
+	}
+
+	// Native sidecars start after the database is ready and stop after the writers
+	if litestreamReplicating(sqliteDB) && r.NativeSidecars {
+		litestreamContainer := nativeSidecar(r.buildLitestreamContainer(sqliteDB))
+		// Replicate the last writes before the container is stopped
+		litestreamContainer.Lifecycle = &corev1.Lifecycle{
+			PreStop: &corev1.LifecycleHandler{
+				Exec: &corev1.ExecAction{
+					Command: []string{"/bin/sh", "-c", "kill -INT 1; while kill -0 1 2>/dev/null; do sleep 1; done"},
+				},
+			},
+		}
+		initContainers = append(initContainers, litestreamContainer)
+	}
+
+	return initContainers
+}
+
+// nativeSidecar turns a container into a native sidecar, an init container running for
+// the lifetime of the pod
+func nativeSidecar(container corev1.Container) corev1.Container {
+	restartPolicy := corev1.ContainerRestartPolicyAlways
+	container.RestartPolicy = &restartPolicy
+	return container
+}
+
+// buildContainers builds the container specifications
+func (r *SqliteDatabaseReconciler) buildContainers(sqliteDB *databasev1alpha1.SqliteDatabase) []corev1.Container {
+	containers := []corev1.Container{}
+	images := r.resolveImages(sqliteDB)
+
+	// Note: SQLite is now handled by init container for sidecar mode
+
+	// Litestream container if enabled, unless it runs as a native sidecar
+	if litestreamReplicating(sqliteDB) && !r.NativeSidecars {
+		containers = append(containers, r.buildLitestreamContainer(sqliteDB))
 	}
 
 	// sqlite-rest container if enabled
 	if sqliteDB.Spec.SqliteRest != nil && sqliteDB.Spec.SqliteRest.Enabled {
 		sqliteRestContainer := corev1.Container{
-			Name:  "sqlite-rest",
-			Image: "ghcr.io/b4fun/sqlite-rest/server:main",
-			Args:  r.buildSqliteRestArgs(sqliteDB),
-			Ports: r.buildSqliteRestPorts(sqliteDB),
+			Name:            "sqlite-rest",
+			Image:           images.SqliteRest,
+			ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+			Args:            r.buildSqliteRestArgs(sqliteDB),
+			Ports:           r.buildSqliteRestPorts(sqliteDB),
 			VolumeMounts: []corev1.VolumeMount{
 				{
 					Name:      "db-storage",
@@ -547,23 +1117,121 @@ func (r *SqliteDatabaseReconciler) buildContainers(sqliteDB *databasev1alpha1.Sq
 			},
 		}
 
+		if writerLeaseEnabled(sqliteDB) {
+			applyWriterLeaseProbes(&sqliteRestContainer)
+		}
+
 		containers = append(containers, sqliteRestContainer)
 	}
 
+	// Follow the replica of the source of a standby
+	if standbyFollowing(sqliteDB) {
+		containers = append(containers, r.buildStandbyFollowContainer(sqliteDB))
+	}
+
+	// Maintenance tasks executed next to the writer
+	if sqliteDB.Spec.Maintenance != nil {
+		containers = append(containers, r.buildMaintenanceContainer(sqliteDB))
+	}
+
+	// Agent reporting the statistics of the database and running control commands
+	if agentEnabled(sqliteDB) {
+		containers = append(containers, r.buildAgentContainer(sqliteDB))
+	}
+
 	return containers
 }
 
+// buildLitestreamContainer builds the Litestream container replicating the database
+func (r *SqliteDatabaseReconciler) buildLitestreamContainer(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.Container {
+	litestreamContainer := corev1.Container{
+		Name:            "litestream",
+		Image:           r.resolveImages(sqliteDB).Litestream,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"litestream"},
+		Args:            []string{"replicate", "-config", "/etc/litestream/litestream.yml"},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "db-storage",
+				MountPath: "/var/lib/sqlite",
+			},
+			{
+				Name:      "litestream-config",
+				MountPath: "/etc/litestream",
+			},
+		},
+		Env: r.buildLitestreamEnv(sqliteDB),
+	}
+
+	if writerLeaseEnabled(sqliteDB) {
+		applyWriterLeaseProbes(&litestreamContainer)
+	}
+
+	return litestreamContainer
+}
+
+// buildLitestreamEnv builds the environment of the containers running Litestream
+func (r *SqliteDatabaseReconciler) buildLitestreamEnv(sqliteDB *databasev1alpha1.SqliteDatabase) []corev1.EnvVar {
+	var env []corev1.EnvVar
+
+	// Add environment variables for credentials
+	for _, replica := range sqliteDB.Spec.Litestream.Replicas {
+		if replica.Credentials != nil {
+			env = append(env, []corev1.EnvVar{
+				{
+					Name: "LITESTREAM_ACCESS_KEY_ID",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: replica.Credentials.SecretName,
+							},
+							Key: getStringValue(replica.Credentials.AccessKeyField, "access-key"),
+						},
+					},
+				},
+				{
+					Name: "LITESTREAM_SECRET_ACCESS_KEY",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: replica.Credentials.SecretName,
+							},
+							Key: getStringValue(replica.Credentials.SecretKeyField, "secret-key"),
+						},
+					},
+				},
+			}...)
+		}
+	}
+
+	return env
+}
+
 // buildVolumes builds the volume specifications
 func (r *SqliteDatabaseReconciler) buildVolumes(sqliteDB *databasev1alpha1.SqliteDatabase) []corev1.Volume {
-	volumes := []corev1.Volume{
-		{
+	var volumes []corev1.Volume
+
+	// The StatefulSet provides the database volume from its volumeClaimTemplate
+	if ephemeralStorage(sqliteDB) {
+		sizeLimit := resource.MustParse(sqliteDB.Spec.Database.Storage.Size)
+		volumes = append(volumes, corev1.Volume{
+			Name: "db-storage",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{
+					Medium:    sqliteDB.Spec.Database.Storage.Medium,
+					SizeLimit: &sizeLimit,
+				},
+			},
+		})
+	} else if workloadKind(sqliteDB) != workloadKindStatefulSet {
+		volumes = append(volumes, corev1.Volume{
 			Name: "db-storage",
 			VolumeSource: corev1.VolumeSource{
 				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-					ClaimName: fmt.Sprintf("%s-db-storage", sqliteDB.Name),
+					ClaimName: deploymentClaimName(sqliteDB),
 				},
 			},
-		},
+		})
 	}
 
 	// Add init script volume if specified
@@ -580,8 +1248,13 @@ func (r *SqliteDatabaseReconciler) buildVolumes(sqliteDB *databasev1alpha1.Sqlit
 		})
 	}
 
+	// Add data source volume if specified
+	if importsDataSource(sqliteDB) {
+		volumes = append(volumes, buildDataSourceVolumes(sqliteDB)...)
+	}
+
 	// Add Litestream volumes if enabled
-	if sqliteDB.Spec.Litestream != nil && sqliteDB.Spec.Litestream.Enabled {
+	if litestreamReplicating(sqliteDB) {
 		volumes = append(volumes, []corev1.Volume{
 			{
 				Name: "litestream-config",
@@ -596,6 +1269,40 @@ func (r *SqliteDatabaseReconciler) buildVolumes(sqliteDB *databasev1alpha1.Sqlit
 		}...)
 	}
 
+	// Add the Litestream configuration of the source of a standby
+	if sqliteDB.Spec.Standby != nil && !standbyPromoted(sqliteDB) {
+		volumes = append(volumes, corev1.Volume{
+			Name: "standby-config",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: standbyConfigName(sqliteDB),
+					},
+				},
+			},
+		})
+	}
+
+	// Add the results of the maintenance tasks
+	if sqliteDB.Spec.Maintenance != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: "maintenance",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
+	}
+
+	// Add the agent binary shared by its install container and sidecar
+	if agentEnabled(sqliteDB) {
+		volumes = append(volumes, corev1.Volume{
+			Name: "agent",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
+	}
+
 	// Add sqlite-rest volumes if enabled
 	if sqliteDB.Spec.SqliteRest != nil && sqliteDB.Spec.SqliteRest.Enabled {
 		volumes = append(volumes, []corev1.Volume{
@@ -629,36 +1336,112 @@ func (r *SqliteDatabaseReconciler) buildVolumes(sqliteDB *databasev1alpha1.Sqlit
 
 // buildSqliteInitScript generates the SQLite initialization script
 func (r *SqliteDatabaseReconciler) buildSqliteInitScript(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	dbPath := fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name)
+
 	script := `set -e
 mkdir -p /var/lib/sqlite`
 
-	if sqliteDB.Spec.Database.InitScript != nil {
+	// A file on an existing claim must be a database before it is replicated or served
+	if sqliteDB.Spec.Database.Storage.ExistingClaim != "" && sqliteDB.Spec.Database.Encryption == nil {
+		script += fmt.Sprintf(`
+if [ -f %s ]; then
+  if [ "$(head -c 15 %s)" != "SQLite format 3" ] || [ "$(sqlite3 %s "PRAGMA quick_check;")" != "ok" ]; then
+    echo "%s on claim %s is not a valid SQLite database" >&2
+    exit 1
+  fi
+  echo "Existing database is valid"
+fi`, dbPath, dbPath, dbPath, dbPath, sqliteDB.Spec.Database.Storage.ExistingClaim)
+	}
+
+	if sqliteDB.Spec.Database.Encryption != nil {
+		// Every SQLCipher session starts with the key, escaped for use in a SQL string literal and
+		// piped to the shell so that it stays out of the command lines of the pod
 		script += fmt.Sprintf(`
-if [ ! -f /var/lib/sqlite/%s ]; then
+KEY_SQL="PRAGMA key = '$(printf '%%s' "$%s" | sed "s/'/''/g")';"`, encryptionKeyEnv)
+	}
+
+	// Import the data source into a new database, which is then opened like an existing one.
+	// Masked data sources are imported by the masking Job instead.
+	if importsDataSource(sqliteDB) {
+		script += buildDataSourceImportScript(sqliteDB, dbPath)
+	} else if len(sqliteDB.Spec.Database.Masking) > 0 {
+		script += fmt.Sprintf(`
+if [ ! -f %s ]; then
+  echo "Database not created yet, waiting for Job %s to import and mask the data source" >&2
+  exit 1
+fi`, dbPath, maskingJobName(sqliteDB))
+	}
+
+	if sqliteDB.Spec.Database.Encryption != nil {
+		script += fmt.Sprintf(`
+if [ -f %s ]; then
+  if ! printf '%%s\nSELECT count(*) FROM sqlite_master;\n' "$KEY_SQL" | sqlcipher %s > /dev/null; then
+    echo "Unable to open encrypted database, check the encryption key" >&2
+    exit 1
+  fi
+  echo "Encrypted database opened successfully"
+elif [ -f /init/init.sql ]; then
+  echo "Initializing encrypted database with init script..."
+  { echo "$KEY_SQL"; cat /init/init.sql; } | sqlcipher %s > /dev/null
+else
+  echo "Creating empty encrypted database..."
+  printf '%%s\nPRAGMA journal_mode = WAL;\n' "$KEY_SQL" | sqlcipher %s > /dev/null
+fi`, dbPath, dbPath, dbPath, dbPath)
+	} else if sqliteDB.Spec.Database.InitScript != nil {
+		script += fmt.Sprintf(`
+if [ ! -f %s ]; then
   echo "Initializing database with init script..."
-  sqlite3 /var/lib/sqlite/%s < /init/init.sql
-fi`, sqliteDB.Spec.Database.Name, sqliteDB.Spec.Database.Name)
+  sqlite3 %s < /init/init.sql
+fi`, dbPath, dbPath)
 	} else {
 		script += fmt.Sprintf(`
 # Create empty database if no init script
-if [ ! -f /var/lib/sqlite/%s ]; then
+if [ ! -f %s ]; then
   echo "Creating empty database..."
-  sqlite3 /var/lib/sqlite/%s "SELECT 1;"
-fi`, sqliteDB.Spec.Database.Name, sqliteDB.Spec.Database.Name)
+  sqlite3 %s "SELECT 1;"
+fi`, dbPath, dbPath)
 	}
 
 	script += fmt.Sprintf(`
-echo "Database ready at /var/lib/sqlite/%s"
-tail -f /dev/null`, sqliteDB.Spec.Database.Name)
+echo "Database ready at %s"`, dbPath)
 
 	return script
 }
 
+// buildEncryptionKeyEnv builds the environment variable exposing the SQLCipher key from its Secret
+func (r *SqliteDatabaseReconciler) buildEncryptionKeyEnv(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: encryptionKeyEnv,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: sqliteDB.Spec.Database.Encryption.SecretName,
+				},
+				Key: getStringValue(sqliteDB.Spec.Database.Encryption.KeyField, "key"),
+			},
+		},
+	}
+}
+
 // buildSqliteRestArgs builds the sqlite-rest container arguments
 func (r *SqliteDatabaseReconciler) buildSqliteRestArgs(sqliteDB *databasev1alpha1.SqliteDatabase) []string {
+	return r.buildSqliteRestArgsWithDSN(sqliteDB, sqliteRestDSN(sqliteDB))
+}
+
+// sqliteRestDSN returns the DSN of the database for sqlite-rest, read-only on a standby
+func sqliteRestDSN(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	dsn := fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name)
+	if standbyFollowing(sqliteDB) {
+		dsn = fmt.Sprintf("file:%s?mode=ro", dsn)
+	}
+	return dsn
+}
+
+// buildSqliteRestArgsWithDSN builds the sqlite-rest container arguments serving the DSN
+func (r *SqliteDatabaseReconciler) buildSqliteRestArgsWithDSN(sqliteDB *databasev1alpha1.SqliteDatabase, dsn string) []string {
 	args := []string{
 		"serve",
-		"--db-dsn", fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name),
+		"--db-dsn", dsn,
 		"--http-addr", fmt.Sprintf(":%d", sqliteDB.Spec.SqliteRest.Port),
 	}
 
@@ -754,6 +1537,12 @@ func (r *SqliteDatabaseReconciler) reconcileIngress(ctx context.Context, sqliteD
 		return fmt.Errorf("ingress host is required when ingress is enabled")
 	}
 
+	// The LiteFS replicas reject writes, so every request is sent to the primary
+	backend := fmt.Sprintf("%s-service", sqliteDB.Name)
+	if liteFSEnabled(sqliteDB) {
+		backend = primaryServiceName(sqliteDB)
+	}
+
 	ingress := &networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      fmt.Sprintf("%s-ingress", sqliteDB.Name),
@@ -776,7 +1565,7 @@ func (r *SqliteDatabaseReconciler) reconcileIngress(ctx context.Context, sqliteD
 									PathType: &[]networkingv1.PathType{networkingv1.PathTypePrefix}[0],
 									Backend: networkingv1.IngressBackend{
 										Service: &networkingv1.IngressServiceBackend{
-											Name: fmt.Sprintf("%s-service", sqliteDB.Name),
+											Name: backend,
 											Port: networkingv1.ServiceBackendPort{
 												Number: 8080,
 											},
@@ -816,26 +1605,23 @@ func (r *SqliteDatabaseReconciler) reconcileIngress(ctx context.Context, sqliteD
 
 // updateStatus updates the status of the SqliteDatabase
 func (r *SqliteDatabaseReconciler) updateStatus(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
-	// Check deployment status
-	deployment := &appsv1.Deployment{}
-	err := r.Get(ctx, types.NamespacedName{
-		Name:      sqliteDB.Name,
-		Namespace: sqliteDB.Namespace,
-	}, deployment)
+	// Check workload status
+	kind := workloadKind(sqliteDB)
+	readyReplicas, err := r.getReadyReplicas(ctx, sqliteDB)
 
 	if err != nil {
 		if errors.IsNotFound(err) {
 			sqliteDB.Status.Phase = "Pending"
-			sqliteDB.Status.Message = "Deployment not found"
+			sqliteDB.Status.Message = fmt.Sprintf("%s not found", kind)
 		} else {
 			sqliteDB.Status.Phase = "Failed"
-			sqliteDB.Status.Message = fmt.Sprintf("Failed to get deployment: %v", err)
+			sqliteDB.Status.Message = fmt.Sprintf("Failed to get %s: %v", strings.ToLower(kind), err)
 		}
 	} else {
-		if deployment.Status.ReadyReplicas > 0 {
+		if readyReplicas > 0 {
 			sqliteDB.Status.Phase = "Running"
 			sqliteDB.Status.Message = "Database is running successfully"
-			sqliteDB.Status.Replicas = deployment.Status.ReadyReplicas
+			sqliteDB.Status.Replicas = readyReplicas
 
 			// Update endpoints
 			if sqliteDB.Spec.SqliteRest != nil && sqliteDB.Spec.SqliteRest.Enabled {
@@ -845,18 +1631,74 @@ func (r *SqliteDatabaseReconciler) updateStatus(ctx context.Context, sqliteDB *d
 					Rest: &restURL,
 				}
 
+				// The LiteFS replicas reject writes, which go to the primary
+				if liteFSEnabled(sqliteDB) {
+					primaryURL := fmt.Sprintf("http://%s.%s.svc.cluster.local:%d",
+						primaryServiceName(sqliteDB), sqliteDB.Namespace, sqliteDB.Spec.SqliteRest.Port)
+					sqliteDB.Status.Endpoints.Primary = &primaryURL
+				}
+
 				if sqliteDB.Spec.SqliteRest.Metrics != nil && sqliteDB.Spec.SqliteRest.Metrics.Enabled {
 					metricsURL := fmt.Sprintf("http://%s-service.%s.svc.cluster.local:%d",
 						sqliteDB.Name, sqliteDB.Namespace, sqliteDB.Spec.SqliteRest.Metrics.Port)
 					sqliteDB.Status.Endpoints.Metrics = &metricsURL
 				}
 			}
+		} else if kind == workloadKindNone {
+			sqliteDB.Status.Phase = "Pending"
+			sqliteDB.Status.Message = fmt.Sprintf("Waiting for an application pod annotated with %s", DatabaseAnnotation)
 		} else {
 			sqliteDB.Status.Phase = "Pending"
-			sqliteDB.Status.Message = "Deployment is starting"
+			sqliteDB.Status.Message = fmt.Sprintf("%s is starting", kind)
 		}
 	}
 
+	// Report the images resolved for the pod
+	images := r.resolveImages(sqliteDB)
+	litestreamUsed := litestreamReplicating(sqliteDB) || standbyFollowing(sqliteDB)
+	if !litestreamUsed || liteFSEnabled(sqliteDB) {
+		images.Litestream = ""
+	}
+	if sqliteDB.Spec.SqliteRest == nil || !sqliteDB.Spec.SqliteRest.Enabled {
+		images.SqliteRest = ""
+	}
+	if !writerLeaseEnabled(sqliteDB) && !(liteFSEnabled(sqliteDB) && liteFSConfig(sqliteDB).Lease == liteFSLeaseKubernetes) {
+		images.Operator = ""
+	}
+	if liteFSEnabled(sqliteDB) {
+		images.Sqlite = ""
+	} else {
+		images.LiteFS = ""
+	}
+	sqliteDB.Status.Images = &images
+
+	// Report the pod holding the writer lease
+	if writerLeaseEnabled(sqliteDB) {
+		writer, err := r.getWriterLeaseHolder(ctx, sqliteDB)
+		if err != nil {
+			return err
+		}
+		sqliteDB.Status.Writer = writer
+	} else {
+		sqliteDB.Status.Writer = ""
+	}
+
+	// Report the lag of a standby and its promotion
+	if err := r.updateStandbyStatus(ctx, sqliteDB); err != nil {
+		return err
+	}
+
+	// Report the LiteFS primary and the position of each pod
+	if liteFSEnabled(sqliteDB) {
+		liteFS, err := r.getLiteFSStatus(ctx, sqliteDB)
+		if err != nil {
+			return err
+		}
+		sqliteDB.Status.LiteFS = liteFS
+	} else {
+		sqliteDB.Status.LiteFS = nil
+	}
+
 	// Update conditions
 	condition := metav1.Condition{
 		Type:               "Ready",
@@ -866,27 +1708,41 @@ func (r *SqliteDatabaseReconciler) updateStatus(ctx context.Context, sqliteDB *d
 		Message:            sqliteDB.Status.Message,
 	}
 
-	if sqliteDB.Status.Phase != "Running" {
+	if sqliteDB.Status.Phase == "Standby" {
+		condition.Reason = "Standby"
+	} else if sqliteDB.Status.Phase != "Running" {
 		condition.Status = metav1.ConditionFalse
 		condition.Reason = "ReconciliationInProgress"
 	}
+	setCondition(sqliteDB, condition)
+
+	// Report whether the database survives the loss of its pod without replication
+	durable := metav1.Condition{
+		Type:               "Durable",
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             "PersistentVolume",
+		Message:            "Database is stored on a persistent volume",
+	}
+	if ephemeralStorage(sqliteDB) {
+		durable.Status = metav1.ConditionFalse
+		durable.Reason = "EphemeralStorage"
+		durable.Message = "Database is stored in an emptyDir, durability depends entirely on Litestream replication"
+	}
+	setCondition(sqliteDB, durable)
 
-	// Update or add condition
-	conditionIndex := -1
+	return r.writeStatus(ctx, sqliteDB)
+}
+
+// setCondition updates or adds a status condition
+func setCondition(sqliteDB *databasev1alpha1.SqliteDatabase, condition metav1.Condition) {
 	for i, c := range sqliteDB.Status.Conditions {
 		if c.Type == condition.Type {
-			conditionIndex = i
-			break
+			sqliteDB.Status.Conditions[i] = condition
+			return
 		}
 	}
-
-	if conditionIndex >= 0 {
-		sqliteDB.Status.Conditions[conditionIndex] = condition
-	} else {
-		sqliteDB.Status.Conditions = append(sqliteDB.Status.Conditions, condition)
-	}
-
-	return r.Status().Update(ctx, sqliteDB)
+	sqliteDB.Status.Conditions = append(sqliteDB.Status.Conditions, condition)
 }
 
 // Helper functions
@@ -898,3 +1754,10 @@ func getStringValue(ptr *string, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getDefaultString(value, defaultValue string) string {
+	if value != "" {
+		return value
+	}
+	return defaultValue
+}
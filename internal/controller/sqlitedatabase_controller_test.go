@@ -18,19 +18,44 @@ package controller
 
 import (
 	"context"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	resourcequantity "k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+	"github.com/sqlite-operator/sqlite-operator/internal/agent"
 )
 
 var _ = Describe("SqliteDatabase Controller", func() {
+	DescribeTable("detecting native sidecar support",
+		func(gitVersion string, expected bool) {
+			Expect(NativeSidecarsSupported(&version.Info{GitVersion: gitVersion})).To(Equal(expected))
+		},
+		Entry("before 1.29", "v1.28.9", false),
+		Entry("from 1.29", "v1.29.0", true),
+		Entry("with a distribution suffix", "v1.30.1-eks-113cf36", true),
+	)
+
 	Context("When reconciling a resource", func() {
 		const resourceName = "test-resource"
 
@@ -94,6 +119,2208 @@ var _ = Describe("SqliteDatabase Controller", func() {
 			Expect(err).NotTo(HaveOccurred())
 			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
+
+			By("Reporting the resolved images in status")
+			resource := &databasev1alpha1.SqliteDatabase{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Images).NotTo(BeNil())
+			Expect(resource.Status.Images.Sqlite).To(Equal(DefaultSqliteImage))
+			Expect(resource.Status.Images.SqliteRest).To(Equal(DefaultSqliteRestImage))
+		})
+
+		It("should apply per-database image overrides", func() {
+			resource := &databasev1alpha1.SqliteDatabase{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			litestreamImage := "registry.internal/litestream@sha256:0123456789abcdef"
+			resource.Spec.Images = &databasev1alpha1.ImagesConfig{Litestream: &litestreamImage}
+			resource.Spec.ImagePullPolicy = corev1.PullIfNotPresent
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Images: Images{Sqlite: "mirror.internal/sqlite3:3.46.1"},
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			podSpec := deployment.Spec.Template.Spec
+			Expect(findContainer(podSpec.InitContainers, "init-db").Image).To(Equal("mirror.internal/sqlite3:3.46.1"))
+			Expect(findContainer(podSpec.Containers, "litestream").Image).To(Equal(litestreamImage))
+			Expect(findContainer(podSpec.Containers, "litestream").ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+		})
+
+		It("should merge pod template overrides onto the generated pod", func() {
+			resource := &databasev1alpha1.SqliteDatabase{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			fsGroup := int64(1000)
+			resource.Spec.PodTemplate = &databasev1alpha1.PodTemplateConfig{
+				Labels: map[string]string{
+					"team":                       "payments",
+					"app.kubernetes.io/instance": "overridden",
+				},
+				NodeSelector:      map[string]string{"disktype": "ssd"},
+				PriorityClassName: "high-priority",
+				SecurityContext:   &corev1.PodSecurityContext{FSGroup: &fsGroup},
+			}
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			template := deployment.Spec.Template
+			Expect(template.Labels).To(HaveKeyWithValue("team", "payments"))
+			Expect(template.Labels).To(HaveKeyWithValue("app.kubernetes.io/instance", resourceName))
+			Expect(template.Spec.NodeSelector).To(HaveKeyWithValue("disktype", "ssd"))
+			Expect(template.Spec.PriorityClassName).To(Equal("high-priority"))
+			Expect(*template.Spec.SecurityContext.FSGroup).To(Equal(fsGroup))
+		})
+
+		It("should guard the writer with a lease and the Recreate strategy", func() {
+			resource := &databasev1alpha1.SqliteDatabase{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.WriterLease = &databasev1alpha1.WriterLeaseConfig{Enabled: true}
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				NativeSidecars: true,
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Strategy.Type).To(Equal(appsv1.RecreateDeploymentStrategyType))
+
+			podSpec := deployment.Spec.Template.Spec
+			Expect(podSpec.ServiceAccountName).To(Equal(resourceName + "-sqlite"))
+			writerLease := podSpec.InitContainers[0]
+			Expect(writerLease.Name).To(Equal("writer-lease"))
+			Expect(*writerLease.RestartPolicy).To(Equal(corev1.ContainerRestartPolicyAlways))
+			Expect(writerLease.StartupProbe).NotTo(BeNil())
+			Expect(findContainer(podSpec.InitContainers, "acquire-lease")).To(BeNil())
+			Expect(findContainer(podSpec.InitContainers, "litestream").LivenessProbe).NotTo(BeNil())
+			Expect(findContainer(podSpec.Containers, "sqlite-rest").LivenessProbe).NotTo(BeNil())
+
+			lease := &coordinationv1.Lease{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      resourceName + "-writer",
+				Namespace: "default",
+			}, lease)).To(Succeed())
+		})
+
+		It("should leave the writer lease off unless it is enabled", func() {
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			podSpec := deployment.Spec.Template.Spec
+			Expect(podSpec.ServiceAccountName).To(BeEmpty())
+			Expect(findContainer(podSpec.InitContainers, "writer-lease")).To(BeNil())
+			Expect(findContainer(podSpec.Containers, "litestream").LivenessProbe).To(BeNil())
+
+			By("refusing the lease without native sidecars")
+			resource := &databasev1alpha1.SqliteDatabase{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.WriterLease = &databasev1alpha1.WriterLeaseConfig{Enabled: true}
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Phase).To(Equal("Failed"))
+			Expect(resource.Status.Message).To(Equal(noNativeSidecarsMessage))
+		})
+
+		It("should refuse to shrink or expand without StorageClass support", func() {
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("shrinking the volume")
+			resource := &databasev1alpha1.SqliteDatabase{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.Database.Storage.Size = "512Mi"
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Storage.Requested).To(Equal("1Gi"))
+			resized := meta.FindStatusCondition(resource.Status.Conditions, "StorageResized")
+			Expect(resized).NotTo(BeNil())
+			Expect(resized.Reason).To(Equal("ShrinkRefused"))
+
+			By("expanding a volume without a StorageClass")
+			resource.Spec.Database.Storage.Size = "2Gi"
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resized = meta.FindStatusCondition(resource.Status.Conditions, "StorageResized")
+			Expect(resized.Reason).To(Equal("ExpansionNotSupported"))
+
+			pvc := &corev1.PersistentVolumeClaim{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      resourceName + "-db-storage",
+				Namespace: "default",
+			}, pvc)).To(Succeed())
+			Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("1Gi"))
+		})
+
+		It("should run Litestream as a native sidecar when the cluster supports it", func() {
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				NativeSidecars: true,
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			podSpec := deployment.Spec.Template.Spec
+			Expect(findContainer(podSpec.Containers, "litestream")).To(BeNil())
+			Expect(findContainer(podSpec.Containers, "writer-lease")).To(BeNil())
+
+			litestream := findContainer(podSpec.InitContainers, "litestream")
+			Expect(litestream).NotTo(BeNil())
+			Expect(*litestream.RestartPolicy).To(Equal(corev1.ContainerRestartPolicyAlways))
+			Expect(litestream.Lifecycle.PreStop.Exec).NotTo(BeNil())
+			Expect(podSpec.InitContainers[len(podSpec.InitContainers)-1].Name).To(Equal("litestream"))
 		})
 	})
-})
+
+	Context("When the workload is a StatefulSet", func() {
+		const resourceName = "statefulset-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			By("creating a SqliteDatabase run by a Deployment")
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "test.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &databasev1alpha1.SqliteDatabase{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should replace the Deployment with a StatefulSet and a headless Service", func() {
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})).To(Succeed())
+
+			By("switching the workload kind")
+			resource := &databasev1alpha1.SqliteDatabase{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.Workload = &databasev1alpha1.WorkloadConfig{Kind: "StatefulSet"}
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			statefulSet := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, statefulSet)).To(Succeed())
+			Expect(statefulSet.Spec.ServiceName).To(Equal(resourceName + "-headless"))
+			Expect(statefulSet.Spec.VolumeClaimTemplates).To(HaveLen(1))
+			Expect(statefulSet.Spec.VolumeClaimTemplates[0].Name).To(Equal("db-storage"))
+			for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+				Expect(volume.Name).NotTo(Equal("db-storage"))
+			}
+
+			service := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      resourceName + "-headless",
+				Namespace: "default",
+			}, service)).To(Succeed())
+			Expect(service.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Message).To(Equal("StatefulSet is starting"))
+		})
+
+		It("should run Litestream in exec mode only without a workload", func() {
+			resource := &databasev1alpha1.SqliteDatabase{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.Litestream = &databasev1alpha1.LitestreamConfig{Enabled: true, Mode: litestreamModeExec}
+			for _, kind := range []string{"Deployment", "StatefulSet"} {
+				resource.Spec.Workload = &databasev1alpha1.WorkloadConfig{Kind: kind}
+				Expect(k8sClient.Update(ctx, resource)).NotTo(Succeed())
+			}
+			resource.Spec.Workload = &databasev1alpha1.WorkloadConfig{Kind: "None"}
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+		})
+	})
+
+	Context("When the storage is ephemeral", func() {
+		const resourceName = "ephemeral-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		newResource := func(replicas []databasev1alpha1.ReplicaConfig) *databasev1alpha1.SqliteDatabase {
+			return &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "cache.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size:   "256Mi",
+							Type:   "ephemeral",
+							Medium: corev1.StorageMediumMemory,
+						},
+					},
+					Litestream: &databasev1alpha1.LitestreamConfig{
+						Enabled:  true,
+						Replicas: replicas,
+					},
+				},
+			}
+		}
+
+		It("should require a Litestream replica", func() {
+			Expect(k8sClient.Create(ctx, newResource(nil))).NotTo(Succeed())
+		})
+
+		It("should restore into an emptyDir instead of a PVC", func() {
+			Expect(k8sClient.Create(ctx, newResource([]databasev1alpha1.ReplicaConfig{
+				{Type: "s3", Bucket: "cache-backups"},
+			}))).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, types.NamespacedName{
+				Name:      resourceName + "-db-storage",
+				Namespace: "default",
+			}, &corev1.PersistentVolumeClaim{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			podSpec := deployment.Spec.Template.Spec
+			Expect(podSpec.Volumes[0].Name).To(Equal("db-storage"))
+			Expect(podSpec.Volumes[0].EmptyDir).NotTo(BeNil())
+			Expect(podSpec.Volumes[0].EmptyDir.Medium).To(Equal(corev1.StorageMediumMemory))
+			Expect(podSpec.Volumes[0].EmptyDir.SizeLimit.String()).To(Equal("256Mi"))
+
+			By("refusing to start empty when the replica does not exist")
+			restoreDB := findContainer(podSpec.InitContainers, "restore-db")
+			Expect(restoreDB).NotTo(BeNil())
+			Expect(restoreDB.Args).NotTo(ContainElement("-if-replica-exists"))
+
+			resource := &databasev1alpha1.SqliteDatabase{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			durable := meta.FindStatusCondition(resource.Status.Conditions, "Durable")
+			Expect(durable).NotTo(BeNil())
+			Expect(durable.Status).To(Equal(metav1.ConditionFalse))
+			Expect(durable.Reason).To(Equal("EphemeralStorage"))
+
+			By("starting empty once allowed to")
+			resource.Spec.Database.Storage.AllowEmpty = true
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(findContainer(deployment.Spec.Template.Spec.InitContainers, "restore-db").Args).To(ContainElement("-if-replica-exists"))
+
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should only allow an empty start with ephemeral storage", func() {
+			resource := newResource([]databasev1alpha1.ReplicaConfig{{Type: "s3", Bucket: "cache-backups"}})
+			resource.Spec.Database.Storage.Type = "persistent"
+			resource.Spec.Database.Storage.Medium = ""
+			resource.Spec.Database.Storage.AllowEmpty = true
+			Expect(k8sClient.Create(ctx, resource)).NotTo(Succeed())
+		})
+	})
+
+	Context("When storage autoGrow is enabled", func() {
+		const resourceName = "growing-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should grow the claim when the database nears its capacity", func() {
+			storageClass := &storagev1.StorageClass{
+				ObjectMeta:           metav1.ObjectMeta{Name: "expandable"},
+				Provisioner:          "example.com/disk",
+				AllowVolumeExpansion: ptr.To(true),
+			}
+			Expect(k8sClient.Create(ctx, storageClass)).To(Succeed())
+
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "app.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size:         "1Gi",
+							StorageClass: ptr.To("expandable"),
+							AutoGrow: &databasev1alpha1.AutoGrowConfig{
+								Enabled: true,
+								MaxSize: "3Gi",
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			executor := &fakePodExecutor{output: "900000000\n100000000\n"}
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Executor: executor,
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("binding the claim and running the pod")
+			pvc := &corev1.PersistentVolumeClaim{}
+			pvcName := types.NamespacedName{Name: resourceName + "-db-storage", Namespace: "default"}
+			Expect(k8sClient.Get(ctx, pvcName, pvc)).To(Succeed())
+			pvc.Status.Phase = corev1.ClaimBound
+			pvc.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resourcequantity.MustParse("1Gi")}
+			Expect(k8sClient.Status().Update(ctx, pvc)).To(Succeed())
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-0",
+					Namespace: "default",
+					Labels: map[string]string{
+						"app.kubernetes.io/name":     "sqlite-database",
+						"app.kubernetes.io/instance": resourceName,
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:         "litestream",
+						Image:        DefaultLitestreamImage,
+						VolumeMounts: []corev1.VolumeMount{{Name: "db-storage", MountPath: "/var/lib/sqlite"}},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			pod.Status.Phase = corev1.PodRunning
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+				Name:  "litestream",
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(autoGrowInterval))
+			Expect(executor.container).To(Equal("litestream"))
+
+			Expect(k8sClient.Get(ctx, pvcName, pvc)).To(Succeed())
+			Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("2Gi"))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Storage.Used).To(Equal("1000000000"))
+			Expect(resource.Status.Storage.History).To(HaveLen(1))
+			Expect(resource.Status.Storage.History[0].From).To(Equal("1Gi"))
+			Expect(resource.Status.Storage.History[0].To).To(Equal("2Gi"))
+			resized := meta.FindStatusCondition(resource.Status.Conditions, "StorageResized")
+			Expect(resized.Reason).To(Equal("Resizing"))
+
+			Expect(k8sClient.Delete(ctx, pod)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, storageClass)).To(Succeed())
+		})
+	})
+
+	Context("When the StorageClass changes", func() {
+		const resourceName = "migrating-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should copy the database to a new claim once approved", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "app.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size:         "1Gi",
+							StorageClass: ptr.To("juicefs"),
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("changing the StorageClass without approval")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.Database.Storage.StorageClass = ptr.To("nvme")
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			migrated := meta.FindStatusCondition(resource.Status.Conditions, "StorageMigrated")
+			Expect(migrated).NotTo(BeNil())
+			Expect(migrated.Reason).To(Equal("ApprovalRequired"))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})).To(Succeed())
+
+			By("approving the migration")
+			resource.Annotations = map[string]string{"sqlite.io/storage-class-migration": "approved"}
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).NotTo(BeZero())
+
+			err = k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			pvc := &corev1.PersistentVolumeClaim{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      resourceName + "-db-storage-nvme",
+				Namespace: "default",
+			}, pvc)).To(Succeed())
+			Expect(*pvc.Spec.StorageClassName).To(Equal("nvme"))
+
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      resourceName + "-storage-migration",
+				Namespace: "default",
+			}, job)).To(Succeed())
+			podSpec := job.Spec.Template.Spec
+			Expect(podSpec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal(resourceName + "-db-storage"))
+			Expect(podSpec.Volumes[1].PersistentVolumeClaim.ClaimName).To(Equal(resourceName + "-db-storage-nvme"))
+			Expect(podSpec.Containers[0].Args[0]).To(ContainSubstring("PRAGMA integrity_check"))
+
+			By("recording the new claim on the SqliteDatabase rather than in its status alone")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(controllerReconciler.annotateDatabaseClaim(ctx, resource, resourceName+"-db-storage-nvme")).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Status = databasev1alpha1.SqliteDatabaseStatus{}
+			Expect(deploymentClaimName(resource)).To(Equal(resourceName + "-db-storage-nvme"))
+
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should release the previous claim before migrating back to its StorageClass", func() {
+			const backName = "remigrating-resource"
+			backNamespacedName := types.NamespacedName{Name: backName, Namespace: "default"}
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      backName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "app.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size:         "1Gi",
+							StorageClass: ptr.To("juicefs"),
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			reconcileDatabase := func() {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: backNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			reconcileDatabase()
+
+			By("recording a completed migration to another StorageClass")
+			migrated := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      backName + "-db-storage-nvme",
+					Namespace: "default",
+				},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+					StorageClassName: ptr.To("nvme"),
+					Resources: corev1.VolumeResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resourcequantity.MustParse("1Gi")},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, migrated)).To(Succeed())
+			Expect(k8sClient.Get(ctx, backNamespacedName, resource)).To(Succeed())
+			Expect(controllerReconciler.annotateDatabaseClaim(ctx, resource, migrated.Name)).To(Succeed())
+			resource.Status.Storage = &databasev1alpha1.StorageStatus{
+				Claim:         migrated.Name,
+				PreviousClaim: backName + "-db-storage",
+			}
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Get(ctx, backNamespacedName, resource)).To(Succeed())
+			resource.Spec.Database.Storage.StorageClass = ptr.To("nvme")
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			reconcileDatabase()
+
+			By("refusing to migrate back while the previous claim is retained")
+			Expect(k8sClient.Get(ctx, backNamespacedName, resource)).To(Succeed())
+			resource.Spec.Database.Storage.StorageClass = ptr.To("juicefs")
+			resource.Annotations["sqlite.io/storage-class-migration"] = "approved"
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			reconcileDatabase()
+
+			Expect(k8sClient.Get(ctx, backNamespacedName, resource)).To(Succeed())
+			condition := meta.FindStatusCondition(resource.Status.Conditions, "StorageMigrated")
+			Expect(condition.Reason).To(Equal("PreviousClaimRetained"))
+			Expect(resource.Status.Storage.PreviousClaim).To(Equal(backName + "-db-storage"))
+			Expect(k8sClient.Get(ctx, backNamespacedName, &appsv1.Deployment{})).To(Succeed())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: backName + "-storage-migration", Namespace: "default"}, &batchv1.Job{})).NotTo(Succeed())
+
+			By("migrating to a new claim once the previous one is released")
+			resource.Annotations["sqlite.io/storage-class-migration"] = "confirmed"
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			reconcileDatabase()
+			Expect(k8sClient.Get(ctx, backNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Storage.PreviousClaim).To(BeEmpty())
+
+			resource.Annotations["sqlite.io/storage-class-migration"] = "approved"
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			reconcileDatabase()
+
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: backName + "-storage-migration", Namespace: "default"}, job)).To(Succeed())
+			podSpec := job.Spec.Template.Spec
+			Expect(podSpec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal(backName + "-db-storage-nvme"))
+			Expect(podSpec.Volumes[1].PersistentVolumeClaim.ClaimName).To(Equal(backName + "-db-storage-juicefs"))
+
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+	})
+
+	Context("When the database is on an existing claim", func() {
+		const resourceName = "adopted-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should wait for other writers and mount the claim subPath", func() {
+			claim := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "legacy-data",
+					Namespace: "default",
+				},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+					Resources: corev1.VolumeResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resourcequantity.MustParse("5Gi")},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, claim)).To(Succeed())
+
+			// Common labels do not make the pod one of the database's
+			legacyPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "legacy-app",
+					Namespace: "default",
+					Labels: map[string]string{
+						"app.kubernetes.io/instance": resourceName,
+						databaseLabel:                resourceName,
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Image: "legacy-app:latest"}},
+					Volumes: []corev1.Volume{{
+						Name: "data",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "legacy-data"},
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, legacyPod)).To(Succeed())
+
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "legacy.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size:          "1Gi",
+							ExistingClaim: "legacy-data",
+							SubPath:       "sqlite",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Phase).To(Equal("Failed"))
+			Expect(resource.Status.Message).To(ContainSubstring("mounted read-write by pod legacy-app"))
+			err = k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			By("stopping the previous writer")
+			Expect(k8sClient.Delete(ctx, legacyPod)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, types.NamespacedName{
+				Name:      resourceName + "-db-storage",
+				Namespace: "default",
+			}, &corev1.PersistentVolumeClaim{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			podSpec := deployment.Spec.Template.Spec
+			Expect(podSpec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("legacy-data"))
+			initDB := findContainer(podSpec.InitContainers, "init-db")
+			Expect(initDB.VolumeMounts[0].SubPath).To(Equal("sqlite"))
+			Expect(initDB.Args[0]).To(ContainSubstring("PRAGMA quick_check"))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Storage.Requested).To(Equal("5Gi"))
+
+			By("ignoring the pods of its own Deployment")
+			replicaSet := &appsv1.ReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-rs",
+					Namespace: "default",
+				},
+				Spec: appsv1.ReplicaSetSpec{
+					Selector: deployment.Spec.Selector,
+					Template: deployment.Spec.Template,
+				},
+			}
+			Expect(controllerutil.SetControllerReference(deployment, replicaSet, k8sClient.Scheme())).To(Succeed())
+			Expect(k8sClient.Create(ctx, replicaSet)).To(Succeed())
+			ownedPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-pod",
+					Namespace: "default",
+				},
+				Spec: podSpec,
+			}
+			Expect(controllerutil.SetControllerReference(replicaSet, ownedPod, k8sClient.Scheme())).To(Succeed())
+			Expect(k8sClient.Create(ctx, ownedPod)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Phase).NotTo(Equal("Failed"))
+			Expect(k8sClient.Delete(ctx, ownedPod)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, replicaSet)).To(Succeed())
+
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "legacy-data", Namespace: "default"}, claim)).To(Succeed())
+			Expect(claim.OwnerReferences).To(BeEmpty())
+			Expect(k8sClient.Delete(ctx, claim)).To(Succeed())
+		})
+	})
+
+	Context("When the database has a data source", func() {
+		const resourceName = "seeded-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should download, verify and import the dump on first creation", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "staging.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+						DataSource: &databasev1alpha1.DataSourceConfig{
+							URL: &databasev1alpha1.URLDataSource{
+								URL:    "s3://exports/sanitized/seed.sql",
+								SHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+								Region: ptr.To("eu-west-1"),
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			podSpec := deployment.Spec.Template.Spec
+
+			fetch := findContainer(podSpec.InitContainers, "fetch-data")
+			Expect(fetch).NotTo(BeNil())
+			Expect(fetch.Args).To(ContainElements(
+				"--url=s3://exports/sanitized/seed.sql",
+				"--output=/data-source/seed.sql",
+				"--region=eu-west-1",
+			))
+
+			initDB := findContainer(podSpec.InitContainers, "init-db")
+			Expect(initDB.Args[0]).To(ContainSubstring("sqlite3 /var/lib/sqlite/staging.db.import < /data-source/seed.sql"))
+			Expect(initDB.VolumeMounts).To(ContainElement(HaveField("Name", "data-source")))
+
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should reject an init script with a data source", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name:       "staging.db",
+						InitScript: ptr.To("schema"),
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+						DataSource: &databasev1alpha1.DataSourceConfig{
+							ConfigMap: &corev1.ConfigMapKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "seed"},
+								Key:                  "seed.sql",
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).NotTo(Succeed())
+		})
+
+		It("should quote the path of the data source in the import script", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "staging.db",
+						DataSource: &databasev1alpha1.DataSourceConfig{
+							PersistentVolumeClaim: &databasev1alpha1.ClaimDataSource{
+								ClaimName: "exports",
+								Path:      "it's $(here).sql",
+							},
+						},
+					},
+				},
+			}
+			script := buildDataSourceImportScript(resource, "/var/lib/sqlite/staging.db")
+			Expect(script).To(ContainSubstring(`sqlite3 /var/lib/sqlite/staging.db.import < '/data-source/it'\''s $(here).sql'`))
+			Expect(script).NotTo(ContainSubstring("echo"))
+		})
+
+		It("should reject a claim path leaving the volume", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "staging.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+						DataSource: &databasev1alpha1.DataSourceConfig{
+							PersistentVolumeClaim: &databasev1alpha1.ClaimDataSource{
+								ClaimName: "exports",
+								Path:      "exports/../../etc/passwd",
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).NotTo(Succeed())
+			resource.Spec.Database.DataSource.PersistentVolumeClaim.Path = "/etc/passwd"
+			Expect(k8sClient.Create(ctx, resource)).NotTo(Succeed())
+		})
+	})
+
+	Context("When the data source is masked", func() {
+		const resourceName = "masked-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should build statements for each strategy", func() {
+			sql := buildMaskingSQL([]databasev1alpha1.MaskingRule{
+				{Table: "users", Column: "phone", Strategy: "null"},
+				{Table: "users", Column: "name", Strategy: "fixed", Value: ptr.To("O'Brien")},
+				{Table: "users", Column: "email", Strategy: "fakeEmail"},
+				{Table: "audit log", Strategy: "drop"},
+			})
+			Expect(sql).To(HavePrefix("PRAGMA secure_delete = ON;\nBEGIN;\n"))
+			Expect(sql).To(ContainSubstring(`UPDATE "users" SET "phone" = NULL;`))
+			Expect(sql).To(ContainSubstring(`UPDATE "users" SET "name" = 'O''Brien';`))
+			Expect(sql).To(ContainSubstring(`'@example.com' WHERE "email" IS NOT NULL;`))
+			Expect(sql).To(ContainSubstring(`DROP TABLE IF EXISTS "audit log";`))
+			Expect(sql).To(HaveSuffix("COMMIT;\nVACUUM;\n"))
+		})
+
+		It("should import and mask the data source in a Job before starting the database", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "staging.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+						DataSource: &databasev1alpha1.DataSourceConfig{
+							ConfigMap: &corev1.ConfigMapKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "seed"},
+								Key:                  "seed.sql",
+							},
+						},
+						Masking: []databasev1alpha1.MaskingRule{
+							{Table: "users", Column: "email", Strategy: "hash"},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).NotTo(BeZero())
+
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      resourceName + "-masking",
+				Namespace: "default",
+			}, job)).To(Succeed())
+			container := job.Spec.Template.Spec.Containers[0]
+			Expect(container.Args[0]).To(ContainSubstring("sqlite3 /var/lib/sqlite/staging.db.masking.import < /data-source/seed.sql"))
+			Expect(container.Args[0]).To(ContainSubstring("mv /var/lib/sqlite/staging.db.masking /var/lib/sqlite/staging.db"))
+			Expect(container.Env).To(ContainElement(HaveField("Value",
+				ContainSubstring(`UPDATE "users" SET "email" = lower(hex(sha3(CAST("email" AS TEXT), 256)))`))))
+
+			By("not starting the database before the data source is masked")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})).NotTo(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Phase).To(Equal("Pending"))
+			masked := meta.FindStatusCondition(resource.Status.Conditions, "Masked")
+			Expect(masked).NotTo(BeNil())
+			Expect(masked.Reason).To(Equal("Masking"))
+
+			By("leaving the import to the Job")
+			initDB := findContainer(controllerReconciler.buildInitContainers(resource), "init-db")
+			Expect(initDB.Args[0]).To(ContainSubstring("waiting for Job masked-resource-masking"))
+			Expect(initDB.Args[0]).NotTo(ContainSubstring("/data-source"))
+
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should reject masking without a data source", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "staging.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+						Masking: []databasev1alpha1.MaskingRule{
+							{Table: "users", Column: "email", Strategy: "hash"},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).NotTo(Succeed())
+		})
+	})
+
+	Context("When read replicas are enabled", func() {
+		const resourceName = "read-replicated-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should follow the replica in read-only pods behind their own Service", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "app.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+					},
+					Litestream: &databasev1alpha1.LitestreamConfig{
+						Enabled:  true,
+						Replicas: []databasev1alpha1.ReplicaConfig{{Type: "s3", Bucket: "backups"}},
+					},
+					SqliteRest: &databasev1alpha1.SqliteRestConfig{
+						Enabled: true,
+						Port:    8080,
+					},
+					ReadReplicas: &databasev1alpha1.ReadReplicasConfig{
+						Count: 3,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			readName := types.NamespacedName{Name: resourceName + "-read", Namespace: "default"}
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, readName, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(3)))
+			podSpec := deployment.Spec.Template.Spec
+			Expect(podSpec.Volumes[0].EmptyDir).NotTo(BeNil())
+			Expect(findContainer(podSpec.InitContainers, "restore-replica")).NotTo(BeNil())
+			Expect(findContainer(podSpec.Containers, "follow-replica").Args[0]).To(ContainSubstring("sleep 10"))
+			Expect(findContainer(podSpec.Containers, "sqlite-rest").Args).To(ContainElement("file:/var/lib/sqlite/app.db?mode=ro"))
+
+			By("selecting only the read replicas in the read Service")
+			service := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, readName, service)).To(Succeed())
+			Expect(service.Spec.Selector).To(HaveKeyWithValue("app.kubernetes.io/name", "sqlite-database-read"))
+			primary := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-service", Namespace: "default"}, primary)).To(Succeed())
+			Expect(deployment.Spec.Template.Labels).NotTo(HaveKeyWithValue("app.kubernetes.io/name", primary.Spec.Selector["app.kubernetes.io/name"]))
+
+			By("removing the read replicas when disabled")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.ReadReplicas = nil
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, readName, service)).NotTo(Succeed())
+
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+	})
+
+	Context("When the database is a standby", func() {
+		const resourceName = "standby-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should follow the replica of its source read-only until promoted", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "app.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+					},
+					Litestream: &databasev1alpha1.LitestreamConfig{
+						Enabled:  true,
+						Replicas: []databasev1alpha1.ReplicaConfig{{Type: "s3", Bucket: "dr-backups"}},
+					},
+					SqliteRest: &databasev1alpha1.SqliteRestConfig{
+						Enabled: true,
+						Port:    8080,
+					},
+					Standby: &databasev1alpha1.StandbyConfig{
+						FromReplica: databasev1alpha1.ReplicaDataSource{
+							Replicas: []databasev1alpha1.ReplicaConfig{{Type: "s3", Bucket: "backups"}},
+							Database: "app.db",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(standbyStatusInterval))
+
+			By("restoring the replica of the source without replicating")
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-standby", Namespace: "default"}, configMap)).To(Succeed())
+			Expect(configMap.Data["litestream.yml"]).To(ContainSubstring("s3://backups/"))
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			podSpec := deployment.Spec.Template.Spec
+			Expect(findContainer(podSpec.InitContainers, "restore-standby")).NotTo(BeNil())
+			Expect(findContainer(podSpec.Containers, "follow-standby").Args[0]).To(ContainSubstring("sleep 10"))
+			Expect(findContainer(podSpec.Containers, "litestream")).To(BeNil())
+			Expect(findContainer(podSpec.Containers, "sqlite-rest").Args).To(ContainElement("file:/var/lib/sqlite/app.db?mode=ro"))
+
+			By("restoring once more and replicating to its own replicas when promoted")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.Standby.Promote = true
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			podSpec = deployment.Spec.Template.Spec
+			Expect(findContainer(podSpec.InitContainers, "promote-standby").Args[0]).To(ContainSubstring("touch /var/lib/sqlite/.standby-promoted"))
+			Expect(findContainer(podSpec.Containers, "follow-standby")).To(BeNil())
+			Expect(findContainer(podSpec.Containers, "litestream")).NotTo(BeNil())
+			Expect(findContainer(podSpec.Containers, "sqlite-rest").Args).To(ContainElement("/var/lib/sqlite/app.db"))
+
+			By("refusing to follow the source again")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.Standby.Promote = false
+			Expect(k8sClient.Update(ctx, resource)).NotTo(Succeed())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should not replicate from the first reconcile", func() {
+			firstName := types.NamespacedName{Name: "standby-first-resource", Namespace: "default"}
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      firstName.Name,
+					Namespace: firstName.Namespace,
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "app.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+					},
+					Litestream: &databasev1alpha1.LitestreamConfig{
+						Enabled:  true,
+						Replicas: []databasev1alpha1.ReplicaConfig{{Type: "s3", Bucket: "dr-backups"}},
+					},
+					Standby: &databasev1alpha1.StandbyConfig{
+						FromReplica: databasev1alpha1.ReplicaDataSource{
+							Replicas: []databasev1alpha1.ReplicaConfig{{Type: "s3", Bucket: "backups"}},
+							Database: "app.db",
+						},
+					},
+				},
+			}
+			Expect(litestreamReplicating(resource)).To(BeFalse())
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				NativeSidecars: true,
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: firstName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, firstName, deployment)).To(Succeed())
+			podSpec := deployment.Spec.Template.Spec
+			Expect(findContainer(podSpec.InitContainers, "litestream")).To(BeNil())
+			Expect(findContainer(podSpec.InitContainers, "restore-db")).To(BeNil())
+			Expect(findContainer(podSpec.Containers, "litestream")).To(BeNil())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: firstName.Name + "-litestream-config", Namespace: "default"}, &corev1.ConfigMap{})).NotTo(Succeed())
+
+			Expect(k8sClient.Get(ctx, firstName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+	})
+
+	Context("When backups are verified", func() {
+		const resourceName = "verified-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should restore the replica on a schedule and report the result", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "app.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+					},
+					Litestream: &databasev1alpha1.LitestreamConfig{
+						Enabled: true,
+						Replicas: []databasev1alpha1.ReplicaConfig{
+							{Type: "s3", Bucket: "backups"},
+							{Type: "s3", Bucket: "offsite"},
+						},
+						Verification: &databasev1alpha1.BackupVerificationConfig{
+							Schedule: "0 3 * * *",
+							Replica:  1,
+							Assertions: []databasev1alpha1.BackupAssertion{
+								{Name: "users", Query: "SELECT count(*) > 0 FROM users;"},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(backupVerificationInterval))
+
+			By("restoring the selected replica into scratch storage")
+			name := types.NamespacedName{Name: resourceName + "-backup-verification", Namespace: "default"}
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, name, configMap)).To(Succeed())
+			Expect(configMap.Data["litestream.yml"]).To(ContainSubstring("s3://offsite/"))
+			Expect(configMap.Data["litestream.yml"]).NotTo(ContainSubstring("s3://backups/"))
+			cronJob := &batchv1.CronJob{}
+			Expect(k8sClient.Get(ctx, name, cronJob)).To(Succeed())
+			Expect(cronJob.Spec.Schedule).To(Equal("0 3 * * *"))
+			Expect(cronJob.Spec.ConcurrencyPolicy).To(Equal(batchv1.ForbidConcurrent))
+			podSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+			Expect(findContainer(podSpec.InitContainers, "restore-replica").Args).To(ContainElement("/verify/app.db"))
+			script := findContainer(podSpec.Containers, "verify-backup").Args[0]
+			Expect(script).To(ContainSubstring("PRAGMA integrity_check;"))
+			Expect(script).To(ContainSubstring("SELECT count(*) > 0 FROM users;"))
+
+			By("waiting for the first verification")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			condition := meta.FindStatusCondition(resource.Status.Conditions, backupVerifiedCondition)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("Pending"))
+
+			By("reporting a successful verification")
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-backup-verification-1",
+					Namespace: "default",
+					Labels:    cronJob.Spec.JobTemplate.Labels,
+				},
+				Spec: cronJob.Spec.JobTemplate.Spec,
+			}
+			Expect(k8sClient.Create(ctx, job)).To(Succeed())
+			now := metav1.Now()
+			job.Status.StartTime = &now
+			job.Status.CompletionTime = &now
+			job.Status.Succeeded = 1
+			job.Status.Conditions = []batchv1.JobCondition{
+				{Type: batchv1.JobSuccessCriteriaMet, Status: corev1.ConditionTrue},
+				{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+			}
+			Expect(k8sClient.Status().Update(ctx, job)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, backupVerifiedCondition)).To(BeTrue())
+			Expect(resource.Status.LastVerified).NotTo(BeNil())
+
+			By("removing the CronJob when verification is disabled")
+			resource.Spec.Litestream.Verification = nil
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, name, cronJob))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(meta.FindStatusCondition(resource.Status.Conditions, backupVerifiedCondition)).To(BeNil())
+			Expect(resource.Status.LastVerified).To(BeNil())
+
+			Expect(k8sClient.Delete(ctx, job)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+	})
+
+	Context("When maintenance is scheduled", func() {
+		const resourceName = "maintenance-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should add the maintenance sidecar and schedule the tasks", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "app.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+					},
+					Maintenance: &databasev1alpha1.MaintenanceConfig{
+						IntegrityCheck: &databasev1alpha1.IntegrityCheckTask{Schedule: "0 2 * * *"},
+						Vacuum:         &databasev1alpha1.VacuumTask{Schedule: "0 3 * * 0", Incremental: true, Pages: 100},
+						Checkpoint:     &databasev1alpha1.CheckpointTask{Schedule: "*/15 * * * *"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(result.RequeueAfter).To(BeNumerically("<=", 15*time.Minute))
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			sidecar := findContainer(deployment.Spec.Template.Spec.Containers, maintenanceContainerName)
+			Expect(sidecar).NotTo(BeNil())
+			Expect(sidecar.VolumeMounts).To(ContainElement(HaveField("MountPath", "/var/lib/sqlite")))
+			Expect(sidecar.VolumeMounts).To(ContainElement(HaveField("MountPath", maintenancePath)))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Maintenance).To(HaveLen(3))
+			for _, status := range resource.Status.Maintenance {
+				Expect(status.NextRun).NotTo(BeNil())
+				Expect(status.LastRun).To(BeNil())
+			}
+
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should record the results of the finished tasks", func() {
+			sqliteDB := &databasev1alpha1.SqliteDatabase{
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Maintenance: &databasev1alpha1.MaintenanceConfig{
+						IntegrityCheck: &databasev1alpha1.IntegrityCheckTask{Schedule: "@daily"},
+						Analyze:        &databasev1alpha1.AnalyzeTask{Schedule: "@daily"},
+						Checkpoint:     &databasev1alpha1.CheckpointTask{Schedule: "@hourly"},
+					},
+				},
+				Status: databasev1alpha1.SqliteDatabaseStatus{
+					Maintenance: []databasev1alpha1.MaintenanceTaskStatus{
+						{Task: "integrityCheck"}, {Task: "analyze"}, {Task: "checkpoint"},
+					},
+				},
+			}
+			r := &SqliteDatabaseReconciler{}
+			running := r.recordMaintenanceResults(sqliteDB, `integrityCheck 1700000000 1700000042 0 *** in database main *** Page 5: never used
+checkpoint 1700000000 1700000001 0 1|12|8
+analyze running
+`)
+			Expect(running).To(Equal(map[string]bool{"analyze": true}))
+
+			integrityCheck := findMaintenanceTaskStatus(sqliteDB, "integrityCheck")
+			Expect(integrityCheck.Result).To(Equal("Failed"))
+			Expect(integrityCheck.Duration).To(Equal("42s"))
+			Expect(integrityCheck.Message).To(ContainSubstring("Page 5: never used"))
+			Expect(findMaintenanceTaskStatus(sqliteDB, "checkpoint").Result).To(Equal("Failed"))
+			Expect(findMaintenanceTaskStatus(sqliteDB, "analyze").LastRun).To(BeNil())
+			Expect(maintenanceFailure("checkpoint", 0, "0|12|12")).To(BeEmpty())
+			Expect(maintenanceFailure("integrityCheck", 0, "ok")).To(BeEmpty())
+		})
+	})
+
+	Context("When corrupt databases are restored", func() {
+		const resourceName = "recovery-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should check the database before restoring it and record the restores", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "app.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+					},
+					Litestream: &databasev1alpha1.LitestreamConfig{
+						Enabled:  true,
+						Replicas: []databasev1alpha1.ReplicaConfig{{Type: "s3", Bucket: "backups"}},
+					},
+					Recovery: &databasev1alpha1.RecoveryConfig{
+						AutoRestore:    true,
+						IntegrityCheck: "full",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("quarantining a corrupt database before the replica is restored")
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			initContainers := deployment.Spec.Template.Spec.InitContainers
+			Expect(initContainers[0].Name).To(Equal("check-db"))
+			Expect(initContainers[0].Args[0]).To(ContainSubstring("PRAGMA integrity_check;"))
+			Expect(initContainers[0].Args[0]).To(ContainSubstring("mv \"$db$suffix\" \"$target$suffix\""))
+			Expect(initContainers[1].Name).To(Equal("restore-db"))
+			Expect(initContainers[1].Args[0]).To(ContainSubstring("cat \"$pending\" > /dev/termination-log"))
+
+			By("recording the restore reported by the pod")
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-0",
+					Namespace: "default",
+					Labels: map[string]string{
+						"app.kubernetes.io/name":     "sqlite-database",
+						"app.kubernetes.io/instance": resourceName,
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "litestream", Image: DefaultLitestreamImage}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			pod.Status.InitContainerStatuses = []corev1.ContainerStatus{{
+				Name: "restore-db",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					Message: "quarantinedAs=/var/lib/sqlite/.quarantine/app.db.20250101T030000Z\n" +
+						"detectedAt=2025-01-01T03:00:00Z\n" +
+						"lastModified=2025-01-01T02:59:30Z\n" +
+						"reason=Error: database disk image is malformed (11)\n" +
+						"replicatedUntil=2025-01-01T02:59:00Z\n",
+				}},
+			}}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Recovery).NotTo(BeNil())
+			Expect(resource.Status.Recovery.LastRestore).NotTo(BeNil())
+			Expect(resource.Status.Recovery.LastRestore.QuarantinedAs).To(Equal("/var/lib/sqlite/.quarantine/app.db.20250101T030000Z"))
+			condition := meta.FindStatusCondition(resource.Status.Conditions, recoveredCondition)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Message).To(ContainSubstring("writes from 2025-01-01T02:59:00Z to 2025-01-01T02:59:30Z are lost"))
+
+			Expect(k8sClient.Delete(ctx, pod)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+	})
+
+	Context("When the agent is enabled", func() {
+		const resourceName = "agent-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should run the agent sidecar and record its statistics and commands", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "app.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+					},
+					Agent: &databasev1alpha1.AgentConfig{Enabled: true},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			agentClient := &fakeAgentClient{
+				stats: &agent.Stats{
+					DatabaseBytes:  8192,
+					WALBytes:       4096,
+					PageSize:       4096,
+					PageCount:      2,
+					Tables:         []string{"users"},
+					ProbeBusyCount: 2,
+					Litestream:     &agent.LitestreamPosition{Generation: "0123456789abcdef", Index: 10, Offset: 42},
+				},
+				result: &agent.CommandResult{Command: agent.CommandCheckpoint, Succeeded: true, Output: "0|0|0"},
+			}
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Agent:  agentClient,
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("installing the agent from the operator image and running it as a sidecar")
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			install := findContainer(deployment.Spec.Template.Spec.InitContainers, "install-agent")
+			Expect(install).NotTo(BeNil())
+			Expect(install.Image).To(Equal(DefaultOperatorImage))
+			sidecar := findContainer(deployment.Spec.Template.Spec.Containers, agentContainerName)
+			Expect(sidecar).NotTo(BeNil())
+			Expect(sidecar.Command).To(Equal([]string{"/opt/agent/agent"}))
+			Expect(sidecar.Args).To(ContainElement("--listen=:9090"))
+			Expect(sidecar.Env[0].ValueFrom.SecretKeyRef.Name).To(Equal(resourceName + "-agent"))
+
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-agent", Namespace: "default"}, secret)).To(Succeed())
+			token := string(secret.Data[agentTokenKey])
+			Expect(token).To(HaveLen(64))
+
+			By("polling the agent of the ready pod")
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-0",
+					Namespace: "default",
+					Labels: map[string]string{
+						"app.kubernetes.io/name":     "sqlite-database",
+						"app.kubernetes.io/instance": resourceName,
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: agentContainerName, Image: DefaultSqliteImage}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			pod.Status.Phase = corev1.PodRunning
+			pod.Status.PodIP = "10.0.0.1"
+			pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+				Name:  agentContainerName,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Annotations = map[string]string{AgentCommandAnnotation: agent.CommandCheckpoint}
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(agentPollInterval))
+			Expect(agentClient.endpoint).To(Equal("http://10.0.0.1:9090"))
+			Expect(agentClient.token).To(Equal(token))
+			Expect(agentClient.command).To(Equal(agent.CommandCheckpoint))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Annotations).NotTo(HaveKey(AgentCommandAnnotation))
+			Expect(resource.Status.Agent).NotTo(BeNil())
+			Expect(resource.Status.Agent.Pod).To(Equal(pod.Name))
+			Expect(resource.Status.Agent.LitestreamPosition).To(Equal("0123456789abcdef/0000000a:42"))
+			Expect(resource.Status.Agent.ProbeBusyCount).To(Equal(int64(2)))
+			Expect(resource.Status.Agent.LastCommand).NotTo(BeNil())
+			Expect(resource.Status.Agent.LastCommand.Result).To(Equal("Succeeded"))
+			condition := meta.FindStatusCondition(resource.Status.Conditions, agentReadyCondition)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+
+			By("publishing the statistics reported by the agent")
+			Expect(resource.Status.Database).NotTo(BeNil())
+			Expect(resource.Status.Database.Size).To(Equal("12Ki"))
+			Expect(resource.Status.Database.WALBytes).To(Equal(int64(4096)))
+			Expect(resource.Status.Database.Pod).To(Equal(pod.Name))
+
+			Expect(k8sClient.Delete(ctx, pod)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+	})
+
+	Context("When statistics are collected", func() {
+		const resourceName = "statistics-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should publish the statistics measured in the maintenance sidecar", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "app.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+					},
+					Statistics: &databasev1alpha1.StatisticsConfig{IntervalSeconds: 60},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-0",
+					Namespace: "default",
+					Labels: map[string]string{
+						"app.kubernetes.io/name":     "sqlite-database",
+						"app.kubernetes.io/instance": resourceName,
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: maintenanceContainerName, Image: DefaultSqliteImage}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			pod.Status.Phase = corev1.PodRunning
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+				Name:  maintenanceContainerName,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+			executor := &fakePodExecutor{output: "file 1572864 1735700000\nwal 4096 1735700100\npragmas 4096 384 12 7 3 \n"}
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Executor: executor,
+			}
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Minute))
+			Expect(executor.container).To(Equal(maintenanceContainerName))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			database := resource.Status.Database
+			Expect(database).NotTo(BeNil())
+			Expect(database.Size).To(Equal("1.5Mi"))
+			Expect(database.FileBytes).To(Equal(int64(1572864)))
+			Expect(database.PageCount).To(Equal(int64(384)))
+			Expect(database.FreelistCount).To(Equal(int64(12)))
+			Expect(database.SchemaVersion).To(Equal(int64(7)))
+			Expect(database.TableCount).To(Equal(int32(3)))
+			Expect(database.LastModified.UTC()).To(Equal(time.Unix(1735700100, 0).UTC()))
+
+			Expect(k8sClient.Delete(ctx, pod)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+	})
+
+	Context("When metrics are collected", func() {
+		const resourceName = "metrics-resource"
+
+		ctx := context.Background()
+
+		It("should export the status of the database", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "app.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			now := metav1.Now()
+			replicated := metav1.NewTime(now.Add(-90 * time.Second))
+			verified := metav1.NewTime(time.Unix(1735700000, 0))
+			resource.Status.Phase = "Running"
+			meta.SetStatusCondition(&resource.Status.Conditions, metav1.Condition{
+				Type:    "Ready",
+				Status:  metav1.ConditionTrue,
+				Reason:  "Running",
+				Message: "Database is running",
+			})
+			resource.Status.Database = &databasev1alpha1.DatabaseStatus{
+				Size:      "1.5Mi",
+				FileBytes: 1572864,
+				WALBytes:  4096,
+				UpdatedAt: &now,
+			}
+			resource.Status.Backups = []databasev1alpha1.ReplicaBackupStatus{{
+				URL:             "s3://backups/app.db",
+				ReplicatedUntil: &replicated,
+			}}
+			resource.Status.LastVerified = &verified
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+			registry := prometheus.NewPedanticRegistry()
+			Expect(registry.Register(newDatabaseCollector(k8sClient))).To(Succeed())
+			families, err := registry.Gather()
+			Expect(err).NotTo(HaveOccurred())
+
+			labels := map[string]string{"namespace": "default", "name": resourceName}
+			Expect(metricValue(families, "sqlite_operator_database_phase",
+				map[string]string{"namespace": "default", "name": resourceName, "phase": "Running"})).To(Equal(1.0))
+			Expect(metricValue(families, "sqlite_operator_database_phase",
+				map[string]string{"namespace": "default", "name": resourceName, "phase": "Failed"})).To(Equal(0.0))
+			Expect(metricValue(families, "sqlite_operator_database_ready", labels)).To(Equal(1.0))
+			Expect(metricValue(families, "sqlite_operator_database_size_bytes", labels)).To(Equal(1572864.0))
+			Expect(metricValue(families, "sqlite_operator_database_wal_bytes", labels)).To(Equal(4096.0))
+			Expect(metricValue(families, "sqlite_operator_replica_seconds_since_last_replication",
+				map[string]string{"namespace": "default", "name": resourceName, "replica": "s3://backups/app.db"})).
+				To(BeNumerically(">=", 90))
+			Expect(metricValue(families, "sqlite_operator_backup_last_verified_timestamp_seconds", labels)).
+				To(Equal(1735700000.0))
+
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should count the errors of each stage of the reconcile", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			}
+			recordReconcileError(resource, "pvc")
+			recordReconcileError(resource, "pvc")
+			Expect(testutil.ToFloat64(reconcileErrors.WithLabelValues("default", resourceName, "pvc"))).To(Equal(2.0))
+
+			forgetDatabaseMetrics("default", resourceName)
+			Expect(testutil.ToFloat64(reconcileErrors.WithLabelValues("default", resourceName, "pvc"))).To(Equal(0.0))
+		})
+	})
+
+	Context("When replicated by LiteFS", func() {
+		const resourceName = "litefs-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should run the LiteFS pods with an elected primary behind the primary Service", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "app.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+					},
+					SqliteRest: &databasev1alpha1.SqliteRestConfig{
+						Enabled: true,
+						Port:    8080,
+					},
+					Replication: &databasev1alpha1.ReplicationConfig{
+						Mode: "litefs",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("running the LiteFS pods in a StatefulSet instead of a Deployment")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})).NotTo(Succeed())
+			statefulSet := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, statefulSet)).To(Succeed())
+			Expect(*statefulSet.Spec.Replicas).To(Equal(int32(3)))
+			Expect(statefulSet.Spec.VolumeClaimTemplates[0].Spec.AccessModes).To(ConsistOf(corev1.ReadWriteOnce))
+			podSpec := statefulSet.Spec.Template.Spec
+			Expect(podSpec.ServiceAccountName).To(Equal(resourceName + "-sqlite"))
+			Expect(*findContainer(podSpec.Containers, "litefs").SecurityContext.Privileged).To(BeTrue())
+
+			By("starting sqlite-rest once LiteFS is mounted with the elected role")
+			Expect(podSpec.Containers[0].Name).To(Equal("litefs-elect"))
+			Expect(podSpec.Containers[1].Name).To(Equal("litefs"))
+			Expect(podSpec.Containers[1].Args[0]).NotTo(ContainSubstring("while"))
+			Expect(podSpec.Containers[1].Lifecycle.PostStart.Exec.Command).To(ContainElement(ContainSubstring("mountpoint -q /litefs")))
+			Expect(findContainer(podSpec.Containers, "litefs-elect").Args).To(ContainElements(
+				"--mode=elect", "--lease-name=litefs-resource-litefs", "--lease-duration=5s",
+				"--peers=litefs-resource-0,litefs-resource-1,litefs-resource-2"))
+			Expect(findContainer(podSpec.Containers, "sqlite-rest").Args).To(ContainElement("/litefs/app.db"))
+
+			By("configuring LiteFS to replicate from the primary Service")
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-litefs-config", Namespace: "default"}, configMap)).To(Succeed())
+			Expect(configMap.Data["litefs.yml"]).To(ContainSubstring("advertise-url: http://litefs-resource-primary.default.svc.cluster.local:20202"))
+
+			By("allowing the pods to hold the Lease, label themselves and restart")
+			role := &rbacv1.Role{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-litefs", Namespace: "default"}, role)).To(Succeed())
+			Expect(role.Rules[1].ResourceNames).To(ConsistOf("litefs-resource-0", "litefs-resource-1", "litefs-resource-2"))
+			Expect(role.Rules[1].Verbs).To(ContainElement("delete"))
+
+			By("routing writes to the elected primary")
+			primary := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-primary", Namespace: "default"}, primary)).To(Succeed())
+			Expect(primary.Spec.Selector).To(HaveKeyWithValue(LiteFSRoleLabel, "primary"))
+
+			By("reporting the holder of the Lease as the primary")
+			lease := &coordinationv1.Lease{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-litefs", Namespace: "default"}, lease)).To(Succeed())
+			lease.Spec.HolderIdentity = ptr.To("litefs-resource-1")
+			Expect(k8sClient.Update(ctx, lease)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.LiteFS.Primary).To(Equal("litefs-resource-1"))
+			Expect(resource.Status.Images.LiteFS).To(Equal(DefaultLiteFSImage))
+
+			By("refusing to switch back to Litestream")
+			resource.Spec.Replication = nil
+			Expect(k8sClient.Update(ctx, resource)).NotTo(Succeed())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should default a bare litefs mode", func() {
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "app.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+					},
+					Replication: &databasev1alpha1.ReplicationConfig{
+						Mode: "litefs",
+					},
+				},
+			}
+
+			By("using the defaults when the litefs block is missing")
+			Expect(resource.Spec.Replication.LiteFS).To(BeNil())
+			Expect(liteFSPodNames(resource)).To(ConsistOf("litefs-resource-0", "litefs-resource-1", "litefs-resource-2"))
+			Expect(liteFSConfig(resource).Lease).To(Equal(liteFSLeaseKubernetes))
+
+			By("keeping the defaults across the status updates of a reconcile")
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			for range 2 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Spec.Replication.LiteFS).NotTo(BeNil())
+			Expect(resource.Spec.Replication.LiteFS.Replicas).To(Equal(int32(3)))
+			statefulSet := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, statefulSet)).To(Succeed())
+			Expect(*statefulSet.Spec.Replicas).To(Equal(int32(3)))
+
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+	})
+
+	Context("When encryption is enabled", func() {
+		const resourceName = "encrypted-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			By("creating an encrypted SqliteDatabase")
+			resource := &databasev1alpha1.SqliteDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: databasev1alpha1.SqliteDatabaseSpec{
+					Database: databasev1alpha1.DatabaseConfig{
+						Name: "secure.db",
+						Storage: databasev1alpha1.StorageConfig{
+							Size: "1Gi",
+						},
+						Encryption: &databasev1alpha1.EncryptionConfig{
+							SecretName: "db-key",
+						},
+					},
+					Litestream: &databasev1alpha1.LitestreamConfig{
+						Enabled: false,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &databasev1alpha1.SqliteDatabase{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should use the SQLCipher image that receives the key", func() {
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Images: Images{SqlcipherSqlite: "registry.internal/sqlcipher:4.6"},
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+
+			podSpec := deployment.Spec.Template.Spec
+			initDB := findContainer(podSpec.InitContainers, "init-db")
+			Expect(initDB.Image).To(Equal("registry.internal/sqlcipher:4.6"))
+			Expect(initDB.Env[0].Name).To(Equal(encryptionKeyEnv))
+			Expect(initDB.Env[0].ValueFrom.SecretKeyRef.Key).To(Equal("key"))
+			Expect(findContainer(podSpec.Containers, "litestream")).To(BeNil())
+		})
+
+		It("should fail without a SQLCipher image", func() {
+			controllerReconciler := &SqliteDatabaseReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			resource := &databasev1alpha1.SqliteDatabase{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Phase).To(Equal("Failed"))
+			Expect(resource.Status.Message).To(Equal(noSqlcipherImageMessage))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})).NotTo(Succeed())
+		})
+
+		It("should reject Litestream and sqlite-rest", func() {
+			resource := &databasev1alpha1.SqliteDatabase{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.SqliteRest = &databasev1alpha1.SqliteRestConfig{Enabled: true, Port: 8080}
+			Expect(k8sClient.Update(ctx, resource)).NotTo(Succeed())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.Litestream.Enabled = true
+			Expect(k8sClient.Update(ctx, resource)).NotTo(Succeed())
+		})
+	})
+})
+
+// findContainer returns the container with the given name, or nil if there is none
+func findContainer(containers []corev1.Container, name string) *corev1.Container {
+	for i := range containers {
+		if containers[i].Name == name {
+			return &containers[i]
+		}
+	}
+	return nil
+}
+
+// fakePodExecutor returns a fixed output and records the container it ran in
+type fakePodExecutor struct {
+	output    string
+	container string
+}
+
+// Exec implements PodExecutor
+func (e *fakePodExecutor) Exec(_ context.Context, _, _, container string, _ []string) (string, error) {
+	e.container = container
+	return e.output, nil
+}
+
+// fakeAgentClient returns fixed statistics and command result and records its last call
+type fakeAgentClient struct {
+	stats    *agent.Stats
+	result   *agent.CommandResult
+	endpoint string
+	token    string
+	command  string
+}
+
+// Stats implements AgentClient
+func (c *fakeAgentClient) Stats(_ context.Context, endpoint, token string) (*agent.Stats, error) {
+	c.endpoint, c.token = endpoint, token
+	return c.stats, nil
+}
+
+// Command implements AgentClient
+func (c *fakeAgentClient) Command(_ context.Context, endpoint, token, command string) (*agent.CommandResult, error) {
+	c.endpoint, c.token, c.command = endpoint, token, command
+	return c.result, nil
+}
+
+// metricValue returns the value of the gauge or counter of the family with the labels, -1 if
+// there is no such series
+func metricValue(families []*dto.MetricFamily, name string, labels map[string]string) float64 {
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			matched := 0
+			for _, label := range metric.GetLabel() {
+				if value, ok := labels[label.GetName()]; ok && value == label.GetValue() {
+					matched++
+				}
+			}
+			if matched != len(labels) {
+				continue
+			}
+			if metric.GetGauge() != nil {
+				return metric.GetGauge().GetValue()
+			}
+			return metric.GetCounter().GetValue()
+		}
+	}
+	return -1
+}
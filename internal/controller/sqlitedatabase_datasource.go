@@ -0,0 +1,319 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+const (
+	// dataSourcePath is where the data source is mounted or downloaded in the init containers
+	dataSourcePath = "/data-source"
+
+	dataSourceFormatSQL = "sql"
+	dataSourceFormatDB  = "db"
+)
+
+// dataSourceFile returns the path of the data source file in the init containers
+func dataSourceFile(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	dataSource := sqliteDB.Spec.Database.DataSource
+
+	var name string
+	switch {
+	case dataSource.ConfigMap != nil:
+		name = dataSource.ConfigMap.Key
+	case dataSource.Secret != nil:
+		name = dataSource.Secret.Key
+	case dataSource.PersistentVolumeClaim != nil:
+		name = dataSource.PersistentVolumeClaim.Path
+	case dataSource.Replica != nil:
+		name = dataSource.Replica.Database
+	case dataSource.URL != nil:
+		name = "data"
+		if u, err := url.Parse(dataSource.URL.URL); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+			name = path.Base(u.Path)
+		}
+	}
+
+	return path.Join(dataSourcePath, name)
+}
+
+// dataSourceFormat returns whether the data source is a SQL dump or a database file
+func dataSourceFormat(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	if format := sqliteDB.Spec.Database.DataSource.Format; format != "" {
+		return format
+	}
+	if sqliteDB.Spec.Database.DataSource.Replica != nil {
+		return dataSourceFormatDB
+	}
+	if strings.HasSuffix(dataSourceFile(sqliteDB), ".sql") {
+		return dataSourceFormatSQL
+	}
+	return dataSourceFormatDB
+}
+
+// buildDataSourceImportScript builds the init script importing the data source when the
+// database does not exist. The import goes through a temporary file so that an interrupted
+// import is retried rather than taken for the database.
+func buildDataSourceImportScript(sqliteDB *databasev1alpha1.SqliteDatabase, dbPath string) string {
+	// The paths come from the spec and are quoted for the shell
+	file := shellQuote(dataSourceFile(sqliteDB))
+	tmpPath := shellQuote(dbPath + ".import")
+	dbPath = shellQuote(dbPath)
+	encrypted := sqliteDB.Spec.Database.Encryption != nil
+
+	var importCmd string
+	switch {
+	case dataSourceFormat(sqliteDB) == dataSourceFormatSQL && encrypted:
+		importCmd = fmt.Sprintf(`{ echo "$KEY_SQL"; cat %s; } | sqlcipher %s > /dev/null`, file, tmpPath)
+	case dataSourceFormat(sqliteDB) == dataSourceFormatSQL:
+		importCmd = fmt.Sprintf(`sqlite3 %s < %s`, tmpPath, file)
+	case encrypted:
+		// The key is checked when the database is opened below
+		importCmd = fmt.Sprintf(`cp %s %s`, file, tmpPath)
+	default:
+		importCmd = fmt.Sprintf(`cp %s %s
+  if [ "$(head -c 15 %s)" != "SQLite format 3" ] || [ "$(sqlite3 %s "PRAGMA quick_check;")" != "ok" ]; then
+    printf 'Data source %%s is not a valid SQLite database\n' %s >&2
+    exit 1
+  fi`, file, tmpPath, tmpPath, tmpPath, file)
+	}
+
+	return fmt.Sprintf(`
+if [ ! -f %s ]; then
+  if [ ! -f %s ]; then
+    printf 'Data source %%s not found\n' %s >&2
+    exit 1
+  fi
+  printf 'Importing data source %%s...\n' %s
+  rm -f %s
+  %s
+  mv %s %s
+fi`, dbPath, file, file, file, tmpPath, importCmd, tmpPath, dbPath)
+}
+
+// buildDataSourceVolumes builds the volume holding the data source, downloaded or restored
+// into an emptyDir for URLs and replicas
+func buildDataSourceVolumes(sqliteDB *databasev1alpha1.SqliteDatabase) []corev1.Volume {
+	dataSource := sqliteDB.Spec.Database.DataSource
+	volume := corev1.Volume{Name: "data-source"}
+
+	switch {
+	case dataSource.ConfigMap != nil:
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: dataSource.ConfigMap.LocalObjectReference,
+			Items:                []corev1.KeyToPath{{Key: dataSource.ConfigMap.Key, Path: dataSource.ConfigMap.Key}},
+		}
+	case dataSource.Secret != nil:
+		volume.Secret = &corev1.SecretVolumeSource{
+			SecretName: dataSource.Secret.Name,
+			Items:      []corev1.KeyToPath{{Key: dataSource.Secret.Key, Path: dataSource.Secret.Key}},
+		}
+	case dataSource.PersistentVolumeClaim != nil:
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: dataSource.PersistentVolumeClaim.ClaimName,
+			ReadOnly:  true,
+		}
+	default:
+		volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+	}
+	volumes := []corev1.Volume{volume}
+
+	// The Litestream configuration of the source database
+	if dataSource.Replica != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: "data-source-config",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: dataSourceConfigName(sqliteDB),
+					},
+				},
+			},
+		})
+	}
+
+	return volumes
+}
+
+// dataSourceConfigName returns the name of the ConfigMap holding the Litestream configuration
+// of the source database
+func dataSourceConfigName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	return fmt.Sprintf("%s-data-source", sqliteDB.Name)
+}
+
+// sourceDatabase returns a copy of the database replicating like the source of its replica
+// data source, from which the Litestream configuration and environment are built
+func sourceDatabase(sqliteDB *databasev1alpha1.SqliteDatabase) *databasev1alpha1.SqliteDatabase {
+	return replicaSourceDatabase(sqliteDB, sqliteDB.Spec.Database.DataSource.Replica)
+}
+
+// replicaSourceDatabase returns a copy of the database replicating to the replica
+func replicaSourceDatabase(sqliteDB *databasev1alpha1.SqliteDatabase, replica *databasev1alpha1.ReplicaDataSource) *databasev1alpha1.SqliteDatabase {
+	source := sqliteDB.DeepCopy()
+	source.Spec.Database.Name = replica.Database
+	source.Spec.Litestream = &databasev1alpha1.LitestreamConfig{
+		Enabled:  true,
+		Replicas: replica.Replicas,
+	}
+	return source
+}
+
+// reconcileDataSourceConfig creates or updates the ConfigMap with the Litestream configuration
+// of the source database
+func (r *SqliteDatabaseReconciler) reconcileDataSourceConfig(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dataSourceConfigName(sqliteDB),
+			Namespace: sqliteDB.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "sqlite-database",
+				"app.kubernetes.io/instance":   sqliteDB.Name,
+				"app.kubernetes.io/managed-by": "sqlite-operator",
+			},
+		},
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		configMap.Data = map[string]string{
+			"litestream.yml": r.buildLitestreamConfig(sourceDatabase(sqliteDB)),
+		}
+		return controllerutil.SetControllerReference(sqliteDB, configMap, r.Scheme)
+	})
+
+	return err
+}
+
+// buildReplicaRestoreContainer builds the init container restoring the replica of the source
+// database at its point in time, skipped once the database exists
+func (r *SqliteDatabaseReconciler) buildReplicaRestoreContainer(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.Container {
+	replica := sqliteDB.Spec.Database.DataSource.Replica
+	dbPath := fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name)
+
+	restore := []string{
+		"litestream", "restore", "-config", "/etc/litestream-source/litestream.yml",
+		"-o", dataSourceFile(sqliteDB),
+	}
+	if replica.Timestamp != nil {
+		restore = append(restore, "-timestamp", replica.Timestamp.UTC().Format(time.RFC3339))
+	}
+	restore = append(restore, fmt.Sprintf("/var/lib/sqlite/%s", replica.Database))
+
+	return corev1.Container{
+		Name:            "restore-source",
+		Image:           r.resolveImages(sqliteDB).Litestream,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"/bin/sh", "-c"},
+		Args: []string{fmt.Sprintf(`if [ -f %s ]; then
+  echo "Database exists, skipping data source"
+  exit 0
+fi
+exec %s`, shellQuote(dbPath), shellJoin(restore))},
+		Env: r.buildLitestreamEnv(sourceDatabase(sqliteDB)),
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "db-storage",
+				MountPath: "/var/lib/sqlite",
+				ReadOnly:  true,
+			},
+			{
+				Name:      "data-source",
+				MountPath: dataSourcePath,
+			},
+			{
+				Name:      "data-source-config",
+				MountPath: "/etc/litestream-source",
+			},
+		},
+	}
+}
+
+// buildFetchContainer builds the init container downloading the data source from its URL
+// and verifying its checksum, skipped once the database exists
+func (r *SqliteDatabaseReconciler) buildFetchContainer(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.Container {
+	source := sqliteDB.Spec.Database.DataSource.URL
+
+	container := corev1.Container{
+		Name:            "fetch-data",
+		Image:           r.resolveImages(sqliteDB).Operator,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"/fetch"},
+		Args: []string{
+			"--url=" + source.URL,
+			"--output=" + dataSourceFile(sqliteDB),
+			"--sha256=" + source.SHA256,
+			fmt.Sprintf("--skip-if-exists=/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name),
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "db-storage",
+				MountPath: "/var/lib/sqlite",
+				ReadOnly:  true,
+			},
+			{
+				Name:      "data-source",
+				MountPath: dataSourcePath,
+			},
+		},
+	}
+	if source.Region != nil {
+		container.Args = append(container.Args, "--region="+*source.Region)
+	}
+	if source.Endpoint != nil {
+		container.Args = append(container.Args, "--endpoint="+*source.Endpoint)
+	}
+
+	if source.Credentials != nil {
+		container.Env = []corev1.EnvVar{
+			{
+				Name: "AWS_ACCESS_KEY_ID",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: source.Credentials.SecretName,
+						},
+						Key: getStringValue(source.Credentials.AccessKeyField, "access-key"),
+					},
+				},
+			},
+			{
+				Name: "AWS_SECRET_ACCESS_KEY",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: source.Credentials.SecretName,
+						},
+						Key: getStringValue(source.Credentials.SecretKeyField, "secret-key"),
+					},
+				},
+			},
+		}
+	}
+
+	return container
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+const (
+	// writerLeasePort serves the lease state for the writers' liveness probes
+	writerLeasePort = 8095
+
+	// writerLeaseRetryPeriodSeconds is the interval between lease acquire/renew attempts
+	writerLeaseRetryPeriodSeconds = 2
+
+	// noNativeSidecarsMessage is reported when the writer lease is enabled on a cluster
+	// without native sidecar containers
+	noNativeSidecarsMessage = "writerLease requires native sidecar containers (Kubernetes 1.29+) to hold the lease while the init containers run"
+)
+
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
+
+// writerLeaseEnabled returns true if the database pod must hold the writer lease. LiteFS
+// elects its primary with a Lease of its own.
+func writerLeaseEnabled(sqliteDB *databasev1alpha1.SqliteDatabase) bool {
+	return sqliteDB.Spec.WriterLease != nil && sqliteDB.Spec.WriterLease.Enabled && !liteFSEnabled(sqliteDB)
+}
+
+// writerLeaseName returns the name of the Lease held by the writer pod
+func writerLeaseName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	return fmt.Sprintf("%s-writer", sqliteDB.Name)
+}
+
+// serviceAccountName returns the name of the ServiceAccount of the database pod
+func serviceAccountName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	return fmt.Sprintf("%s-sqlite", sqliteDB.Name)
+}
+
+// reconcileWriterLease creates or updates the Lease and the RBAC allowing the pod to hold it
+func (r *SqliteDatabaseReconciler) reconcileWriterLease(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	return r.reconcilePodLease(ctx, sqliteDB, writerLeaseName(sqliteDB))
+}
+
+// reconcilePodLease creates or updates a Lease held by the database pods, their
+// ServiceAccount and a Role allowing them to hold the Lease, along with the extra rules
+func (r *SqliteDatabaseReconciler) reconcilePodLease(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase, name string, rules ...rbacv1.PolicyRule) error {
+	labels := map[string]string{
+		"app.kubernetes.io/name":       "sqlite-database",
+		"app.kubernetes.io/instance":   sqliteDB.Name,
+		"app.kubernetes.io/managed-by": "sqlite-operator",
+	}
+
+	// The Lease is created up front so that it is owned by the SqliteDatabase and the pod
+	// only needs access to this one object. The pods fill in the holder.
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: sqliteDB.Namespace,
+		},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, lease, func() error {
+		lease.Labels = labels
+		return controllerutil.SetControllerReference(sqliteDB, lease, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      serviceAccountName(sqliteDB),
+			Namespace: sqliteDB.Namespace,
+		},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, serviceAccount, func() error {
+		serviceAccount.Labels = labels
+		return controllerutil.SetControllerReference(sqliteDB, serviceAccount, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: sqliteDB.Namespace,
+		},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, role, func() error {
+		role.Labels = labels
+		role.Rules = append([]rbacv1.PolicyRule{
+			{
+				APIGroups:     []string{coordinationv1.GroupName},
+				Resources:     []string{"leases"},
+				ResourceNames: []string{name},
+				Verbs:         []string{"get", "update"},
+			},
+		}, rules...)
+		return controllerutil.SetControllerReference(sqliteDB, role, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	roleBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: sqliteDB.Namespace,
+		},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, roleBinding, func() error {
+		roleBinding.Labels = labels
+		roleBinding.RoleRef = rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     role.Name,
+		}
+		roleBinding.Subjects = []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      serviceAccount.Name,
+				Namespace: sqliteDB.Namespace,
+			},
+		}
+		return controllerutil.SetControllerReference(sqliteDB, roleBinding, r.Scheme)
+	})
+
+	return err
+}
+
+// buildLeaseContainer builds the container acquiring and renewing the writer lease
+func (r *SqliteDatabaseReconciler) buildLeaseContainer(sqliteDB *databasev1alpha1.SqliteDatabase, name string) corev1.Container {
+	writerLease := sqliteDB.Spec.WriterLease
+
+	return corev1.Container{
+		Name:            name,
+		Image:           r.resolveImages(sqliteDB).Operator,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"/lease"},
+		Args: []string{
+			"--mode=hold",
+			"--namespace=$(POD_NAMESPACE)",
+			"--lease-name=" + writerLeaseName(sqliteDB),
+			"--identity=$(POD_NAME)",
+			fmt.Sprintf("--lease-duration=%ds", writerLease.LeaseDurationSeconds),
+			fmt.Sprintf("--renew-deadline=%ds", writerLease.RenewDeadlineSeconds),
+			fmt.Sprintf("--retry-period=%ds", writerLeaseRetryPeriodSeconds),
+			fmt.Sprintf("--health-addr=:%d", writerLeasePort),
+		},
+		Env: []corev1.EnvVar{
+			{
+				Name: "POD_NAME",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+				},
+			},
+			{
+				Name: "POD_NAMESPACE",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+				},
+			},
+		},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "writer-lease",
+				ContainerPort: writerLeasePort,
+			},
+		},
+	}
+}
+
+// buildWriterLeaseSidecar builds the native sidecar acquiring and renewing the writer lease.
+// Its startup probe only succeeds once the lease is held, so the init containers after it
+// wait for the lease and run while it is being renewed.
+func (r *SqliteDatabaseReconciler) buildWriterLeaseSidecar(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.Container {
+	container := nativeSidecar(r.buildLeaseContainer(sqliteDB, "writer-lease"))
+	container.StartupProbe = &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: "/",
+				Port: intstr.FromInt32(writerLeasePort),
+			},
+		},
+		PeriodSeconds:  writerLeaseRetryPeriodSeconds,
+		TimeoutSeconds: 1,
+		// Outlast a previous holder that stopped renewing without releasing the lease
+		FailureThreshold: sqliteDB.Spec.WriterLease.LeaseDurationSeconds + 30,
+	}
+	return container
+}
+
+// applyWriterLeaseProbes makes a writer container wait for and depend on the writer lease.
+// The probes are served by the lease holder in the same pod, so the kubelet stops the
+// writer within the renew deadline once the lease is lost, even if the node is partitioned
+// from the API server.
+func applyWriterLeaseProbes(container *corev1.Container) {
+	handler := corev1.ProbeHandler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Path: "/",
+			Port: intstr.FromInt32(writerLeasePort),
+		},
+	}
+
+	container.StartupProbe = &corev1.Probe{
+		ProbeHandler:     handler,
+		PeriodSeconds:    writerLeaseRetryPeriodSeconds,
+		TimeoutSeconds:   1,
+		FailureThreshold: 30,
+	}
+	container.LivenessProbe = &corev1.Probe{
+		ProbeHandler:     handler,
+		PeriodSeconds:    writerLeaseRetryPeriodSeconds,
+		TimeoutSeconds:   1,
+		FailureThreshold: 2,
+	}
+}
+
+// getWriterLeaseHolder returns the pod currently holding the writer lease
+func (r *SqliteDatabaseReconciler) getWriterLeaseHolder(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (string, error) {
+	return r.getLeaseHolder(ctx, sqliteDB, writerLeaseName(sqliteDB))
+}
+
+// getLeaseHolder returns the pod currently holding the named Lease of the database
+func (r *SqliteDatabaseReconciler) getLeaseHolder(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase, name string) (string, error) {
+	lease := &coordinationv1.Lease{}
+	err := r.Get(ctx, types.NamespacedName{
+		Name:      name,
+		Namespace: sqliteDB.Namespace,
+	}, lease)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return getStringValue(lease.Spec.HolderIdentity, ""), nil
+}
@@ -0,0 +1,570 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+const (
+	replicationModeLitestream = "litestream"
+	replicationModeLiteFS     = "litefs"
+
+	liteFSLeaseKubernetes = "kubernetes"
+	liteFSLeaseStatic     = "static"
+
+	// liteFSPort serves the LiteFS API the replicas stream the primary's transactions from
+	liteFSPort = 20202
+
+	// LiteFSRoleLabel is set to primary on the pod elected by the LiteFS Lease and selected
+	// by the <name>-primary Service, and to replica on the other pods
+	LiteFSRoleLabel = "sqlite.io/litefs-role"
+
+	// liteFSStatusInterval is the interval between updates of the primary and positions in status
+	liteFSStatusInterval = 10 * time.Second
+)
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=patch
+
+// liteFSEnabled returns true if the database is replicated by LiteFS
+func liteFSEnabled(sqliteDB *databasev1alpha1.SqliteDatabase) bool {
+	return sqliteDB.Spec.Replication != nil && sqliteDB.Spec.Replication.Mode == replicationModeLiteFS
+}
+
+// liteFSConfig returns the LiteFS configuration of the database with its defaults, which
+// are also set when the litefs block is omitted
+func liteFSConfig(sqliteDB *databasev1alpha1.SqliteDatabase) databasev1alpha1.LiteFSConfig {
+	var liteFS databasev1alpha1.LiteFSConfig
+	if sqliteDB.Spec.Replication != nil && sqliteDB.Spec.Replication.LiteFS != nil {
+		liteFS = *sqliteDB.Spec.Replication.LiteFS
+	}
+	setLiteFSDefaults(&liteFS)
+	return liteFS
+}
+
+// setLiteFSDefaults sets the defaults of the unset LiteFS fields
+func setLiteFSDefaults(liteFS *databasev1alpha1.LiteFSConfig) {
+	if liteFS.Replicas == 0 {
+		liteFS.Replicas = 3
+	}
+	if liteFS.Lease == "" {
+		liteFS.Lease = liteFSLeaseKubernetes
+	}
+	if liteFS.LeaseDurationSeconds == 0 {
+		liteFS.LeaseDurationSeconds = 5
+	}
+	if liteFS.RenewDeadlineSeconds == 0 {
+		liteFS.RenewDeadlineSeconds = 3
+	}
+}
+
+// liteFSLeaseName returns the name of the Lease electing the LiteFS primary
+func liteFSLeaseName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	return fmt.Sprintf("%s-litefs", sqliteDB.Name)
+}
+
+// primaryServiceName returns the name of the Service routing to the LiteFS primary
+func primaryServiceName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	return fmt.Sprintf("%s-primary", sqliteDB.Name)
+}
+
+// liteFSPodName returns the name of the LiteFS pod with the ordinal
+func liteFSPodName(sqliteDB *databasev1alpha1.SqliteDatabase, ordinal int32) string {
+	return fmt.Sprintf("%s-%d", sqliteDB.Name, ordinal)
+}
+
+// liteFSPodNames returns the names of all the LiteFS pods
+func liteFSPodNames(sqliteDB *databasev1alpha1.SqliteDatabase) []string {
+	replicas := liteFSConfig(sqliteDB).Replicas
+	pods := make([]string, 0, replicas)
+	for i := int32(0); i < replicas; i++ {
+		pods = append(pods, liteFSPodName(sqliteDB, i))
+	}
+	return pods
+}
+
+// reconcileLiteFS creates or updates the LiteFS StatefulSet, its configuration, the Lease
+// electing its primary and the Services routing reads to every pod and writes to the primary
+func (r *SqliteDatabaseReconciler) reconcileLiteFS(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	liteFS := liteFSConfig(sqliteDB)
+
+	if sqliteDB.Spec.SqliteRest != nil && sqliteDB.Spec.SqliteRest.Enabled {
+		if err := r.reconcileSqliteRestConfig(ctx, sqliteDB); err != nil {
+			return err
+		}
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-litefs-config", sqliteDB.Name),
+			Namespace: sqliteDB.Namespace,
+		},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		configMap.Labels = map[string]string{
+			"app.kubernetes.io/name":       "sqlite-database",
+			"app.kubernetes.io/instance":   sqliteDB.Name,
+			"app.kubernetes.io/managed-by": "sqlite-operator",
+		}
+		configMap.Data = map[string]string{
+			"litefs.yml": r.buildLiteFSConfig(sqliteDB),
+		}
+		return controllerutil.SetControllerReference(sqliteDB, configMap, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	// The elected pod labels itself as the primary and its peers as replicas, and a pod
+	// whose role changes deletes itself, so it may only patch and delete the LiteFS pods
+	if liteFS.Lease == liteFSLeaseKubernetes {
+		if err := r.reconcilePodLease(ctx, sqliteDB, liteFSLeaseName(sqliteDB), rbacv1.PolicyRule{
+			APIGroups:     []string{""},
+			Resources:     []string{"pods"},
+			ResourceNames: liteFSPodNames(sqliteDB),
+			Verbs:         []string{"get", "patch", "delete"},
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := r.reconcileHeadlessService(ctx, sqliteDB); err != nil {
+		return err
+	}
+	if err := r.reconcileLiteFSStatefulSet(ctx, sqliteDB); err != nil {
+		return err
+	}
+	if err := r.reconcilePrimaryService(ctx, sqliteDB); err != nil {
+		return err
+	}
+
+	// Reads are served by every pod
+	if sqliteDB.Spec.SqliteRest != nil && sqliteDB.Spec.SqliteRest.Enabled {
+		if err := r.reconcileService(ctx, sqliteDB); err != nil {
+			return err
+		}
+	}
+	if sqliteDB.Spec.Ingress != nil && sqliteDB.Spec.Ingress.Enabled {
+		if err := r.reconcileIngress(ctx, sqliteDB); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildLiteFSConfig generates the LiteFS configuration. LiteFS uses its static lease with the
+// candidate flag set by the litefs container from the elected role, and the replicas
+// connect to the primary through the <name>-primary Service.
+func (r *SqliteDatabaseReconciler) buildLiteFSConfig(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	return fmt.Sprintf(`fuse:
+  dir: /litefs
+  allow-other: true
+data:
+  dir: /var/lib/litefs
+exit-on-error: false
+http:
+  addr: ":%[1]d"
+lease:
+  type: static
+  candidate: ${LITEFS_CANDIDATE}
+  advertise-url: http://%[2]s.%[3]s.svc.cluster.local:%[1]d
+`, liteFSPort, primaryServiceName(sqliteDB), sqliteDB.Namespace)
+}
+
+// reconcileLiteFSStatefulSet creates or updates the StatefulSet of the LiteFS pods
+func (r *SqliteDatabaseReconciler) reconcileLiteFSStatefulSet(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sqliteDB.Name,
+			Namespace: sqliteDB.Namespace,
+		},
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, statefulSet, func() error {
+		statefulSet.Labels = map[string]string{
+			"app.kubernetes.io/name":       "sqlite-database",
+			"app.kubernetes.io/instance":   sqliteDB.Name,
+			"app.kubernetes.io/managed-by": "sqlite-operator",
+		}
+		statefulSet.Spec.Replicas = int32Ptr(liteFSConfig(sqliteDB).Replicas)
+		statefulSet.Spec.Template = r.buildLiteFSPodTemplate(sqliteDB)
+
+		// The selector, service name and claim templates are immutable. The pods start
+		// together so that a replica can be elected while pod 0 is down, and each keeps
+		// its copy of the database until the SqliteDatabase is deleted.
+		if statefulSet.CreationTimestamp.IsZero() {
+			statefulSet.Spec.Selector = &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/name":     "sqlite-database",
+					"app.kubernetes.io/instance": sqliteDB.Name,
+				},
+			}
+			statefulSet.Spec.ServiceName = headlessServiceName(sqliteDB)
+			statefulSet.Spec.PodManagementPolicy = appsv1.ParallelPodManagement
+			statefulSet.Spec.PersistentVolumeClaimRetentionPolicy = &appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{
+				WhenDeleted: appsv1.DeletePersistentVolumeClaimRetentionPolicyType,
+				WhenScaled:  appsv1.RetainPersistentVolumeClaimRetentionPolicyType,
+			}
+			statefulSet.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "db-storage",
+						Labels: map[string]string{
+							"app.kubernetes.io/name":       "sqlite-database",
+							"app.kubernetes.io/instance":   sqliteDB.Name,
+							"app.kubernetes.io/managed-by": "sqlite-operator",
+						},
+					},
+					Spec: r.buildPVCSpec(sqliteDB),
+				},
+			}
+		}
+
+		return controllerutil.SetControllerReference(sqliteDB, statefulSet, r.Scheme)
+	})
+
+	return err
+}
+
+// buildLiteFSPodTemplate builds the pod template of the LiteFS pods. The litefs container
+// mounts the FUSE file system holding the database and shares it with sqlite-rest through
+// mount propagation. LiteFS is mounted once per pod, as the candidate or a replica, and
+// sqlite-rest only starts once it is mounted. With the kubernetes lease, the litefs-elect
+// container campaigns for the Lease, writes the role of the pod to the file read by the
+// litefs container once the election is decided, and deletes the pod when its role changes
+// for the StatefulSet to recreate it with the new role.
+func (r *SqliteDatabaseReconciler) buildLiteFSPodTemplate(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.PodTemplateSpec {
+	images := r.resolveImages(sqliteDB)
+	liteFS := liteFSConfig(sqliteDB)
+
+	role := fmt.Sprintf(`[ "$HOSTNAME" = %q ] && echo primary || echo replica`, liteFSPodName(sqliteDB, 0))
+	if liteFS.Lease == liteFSLeaseKubernetes {
+		role = `until [ -s /var/run/litefs/role ]; do sleep 0.1; done; cat /var/run/litefs/role`
+	}
+
+	privileged := true
+	bidirectional := corev1.MountPropagationBidirectional
+	liteFSContainer := corev1.Container{
+		Name:            "litefs",
+		Image:           images.LiteFS,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"/bin/sh", "-c"},
+		Args: []string{fmt.Sprintf(`role=$(%s)
+if [ "$role" = primary ]; then export LITEFS_CANDIDATE=true; else export LITEFS_CANDIDATE=false; fi
+echo "Starting LiteFS as $role"
+exec litefs mount -config /etc/litefs/litefs.yml`, role)},
+		// The containers start in order, each once the hook of the previous one returned
+		Lifecycle: &corev1.Lifecycle{
+			PostStart: &corev1.LifecycleHandler{
+				Exec: &corev1.ExecAction{
+					Command: []string{"/bin/sh", "-c", "until mountpoint -q /litefs; do sleep 0.1; done"},
+				},
+			},
+		},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "litefs",
+				ContainerPort: liteFSPort,
+			},
+		},
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				TCPSocket: &corev1.TCPSocketAction{
+					Port: intstr.FromInt32(liteFSPort),
+				},
+			},
+			PeriodSeconds: 2,
+		},
+		// FUSE needs /dev/fuse and a mount propagated to the other containers
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: &privileged,
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "db-storage",
+				MountPath: "/var/lib/litefs",
+			},
+			{
+				Name:             "litefs",
+				MountPath:        "/litefs",
+				MountPropagation: &bidirectional,
+			},
+			{
+				Name:      "litefs-config",
+				MountPath: "/etc/litefs",
+			},
+			{
+				Name:      "litefs-role",
+				MountPath: "/var/run/litefs",
+			},
+		},
+	}
+	if liteFS.Resources != nil {
+		liteFSContainer.Resources = *liteFS.Resources
+	}
+
+	// litefs-elect starts first, as the litefs container waits for the role it writes
+	var containers []corev1.Container
+	if liteFS.Lease == liteFSLeaseKubernetes {
+		containers = append(containers, corev1.Container{
+			Name:            "litefs-elect",
+			Image:           images.Operator,
+			ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+			Command:         []string{"/lease"},
+			Args: []string{
+				"--mode=elect",
+				"--namespace=$(POD_NAMESPACE)",
+				"--lease-name=" + liteFSLeaseName(sqliteDB),
+				"--identity=$(POD_NAME)",
+				fmt.Sprintf("--lease-duration=%ds", liteFS.LeaseDurationSeconds),
+				fmt.Sprintf("--renew-deadline=%ds", liteFS.RenewDeadlineSeconds),
+				"--retry-period=1s",
+				"--role-file=/var/run/litefs/role",
+				"--role-label=" + LiteFSRoleLabel,
+				"--peers=" + strings.Join(liteFSPodNames(sqliteDB), ","),
+			},
+			Env: []corev1.EnvVar{
+				{
+					Name: "POD_NAME",
+					ValueFrom: &corev1.EnvVarSource{
+						FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+					},
+				},
+				{
+					Name: "POD_NAMESPACE",
+					ValueFrom: &corev1.EnvVarSource{
+						FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+					},
+				},
+			},
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      "litefs-role",
+					MountPath: "/var/run/litefs",
+				},
+			},
+		})
+	}
+	containers = append(containers, liteFSContainer)
+
+	volumes := []corev1.Volume{
+		{
+			Name: "litefs",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+		{
+			Name: "litefs-role",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+		{
+			Name: "litefs-config",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: fmt.Sprintf("%s-litefs-config", sqliteDB.Name),
+					},
+				},
+			},
+		},
+	}
+
+	// sqlite-rest serves the database from the LiteFS mount, the replicas reject writes
+	if sqliteDB.Spec.SqliteRest != nil && sqliteDB.Spec.SqliteRest.Enabled {
+		hostToContainer := corev1.MountPropagationHostToContainer
+		restContainer := corev1.Container{
+			Name:            "sqlite-rest",
+			Image:           images.SqliteRest,
+			ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+			Args:            r.buildSqliteRestArgsWithDSN(sqliteDB, fmt.Sprintf("/litefs/%s", sqliteDB.Spec.Database.Name)),
+			Ports:           r.buildSqliteRestPorts(sqliteDB),
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:             "litefs",
+					MountPath:        "/litefs",
+					MountPropagation: &hostToContainer,
+				},
+			},
+		}
+		if sqliteDB.Spec.SqliteRest.AuthSecret != nil {
+			volumes = append(volumes, corev1.Volume{
+				Name: "sqlite-rest-auth",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: *sqliteDB.Spec.SqliteRest.AuthSecret,
+					},
+				},
+			})
+			restContainer.VolumeMounts = append(restContainer.VolumeMounts, corev1.VolumeMount{
+				Name:      "sqlite-rest-auth",
+				MountPath: "/etc/auth",
+				ReadOnly:  true,
+			})
+		}
+		containers = append(containers, restContainer)
+	}
+
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				"app.kubernetes.io/name":     "sqlite-database",
+				"app.kubernetes.io/instance": sqliteDB.Name,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers:       containers,
+			Volumes:          volumes,
+			ImagePullSecrets: sqliteDB.Spec.ImagePullSecrets,
+		},
+	}
+	if liteFS.Lease == liteFSLeaseKubernetes {
+		template.Spec.ServiceAccountName = serviceAccountName(sqliteDB)
+	}
+	r.applyPodTemplate(sqliteDB, &template)
+
+	// The container security context of the pod template cannot drop the FUSE privileges
+	for i := range template.Spec.Containers {
+		if template.Spec.Containers[i].Name == liteFSContainer.Name {
+			template.Spec.Containers[i].SecurityContext = liteFSContainer.SecurityContext
+		}
+	}
+
+	return template
+}
+
+// reconcilePrimaryService creates or updates the Service routing to the LiteFS primary,
+// used by the replicas to stream its transactions and by the clients to write
+func (r *SqliteDatabaseReconciler) reconcilePrimaryService(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      primaryServiceName(sqliteDB),
+			Namespace: sqliteDB.Namespace,
+		},
+	}
+
+	selector := map[string]string{
+		"app.kubernetes.io/name":     "sqlite-database",
+		"app.kubernetes.io/instance": sqliteDB.Name,
+	}
+	if liteFSConfig(sqliteDB).Lease == liteFSLeaseKubernetes {
+		selector[LiteFSRoleLabel] = "primary"
+	} else {
+		selector[appsv1.StatefulSetPodNameLabel] = liteFSPodName(sqliteDB, 0)
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, service, func() error {
+		service.Labels = map[string]string{
+			"app.kubernetes.io/name":       "sqlite-database",
+			"app.kubernetes.io/instance":   sqliteDB.Name,
+			"app.kubernetes.io/managed-by": "sqlite-operator",
+		}
+		service.Spec.Selector = selector
+		service.Spec.Type = corev1.ServiceTypeClusterIP
+		service.Spec.Ports = []corev1.ServicePort{
+			{
+				Name:       "litefs",
+				Port:       liteFSPort,
+				TargetPort: intstr.FromInt32(liteFSPort),
+			},
+		}
+		if sqliteDB.Spec.SqliteRest != nil && sqliteDB.Spec.SqliteRest.Enabled {
+			service.Spec.Ports = append(service.Spec.Ports, r.buildServicePorts(sqliteDB)...)
+		}
+		return controllerutil.SetControllerReference(sqliteDB, service, r.Scheme)
+	})
+
+	return err
+}
+
+// getLiteFSStatus returns the elected primary and the position of the database on each
+// ready pod. The positions are read from the LiteFS mount when an Executor is set.
+func (r *SqliteDatabaseReconciler) getLiteFSStatus(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (*databasev1alpha1.LiteFSStatus, error) {
+	log := logf.FromContext(ctx)
+
+	status := &databasev1alpha1.LiteFSStatus{
+		Primary: liteFSPodName(sqliteDB, 0),
+	}
+	if liteFSConfig(sqliteDB).Lease == liteFSLeaseKubernetes {
+		holder, err := r.getLeaseHolder(ctx, sqliteDB, liteFSLeaseName(sqliteDB))
+		if err != nil {
+			return nil, err
+		}
+		status.Primary = holder
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(sqliteDB.Namespace), client.MatchingLabels{
+		"app.kubernetes.io/name":     "sqlite-database",
+		"app.kubernetes.io/instance": sqliteDB.Name,
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(pods.Items, func(i, j int) bool { return pods.Items[i].Name < pods.Items[j].Name })
+
+	for _, pod := range pods.Items {
+		if !podReady(&pod) {
+			continue
+		}
+		podStatus := databasev1alpha1.LiteFSPodStatus{
+			Name: pod.Name,
+			Role: "replica",
+		}
+		if pod.Name == status.Primary {
+			podStatus.Role = "primary"
+		}
+		if r.Executor != nil {
+			position, err := r.Executor.Exec(ctx, pod.Namespace, pod.Name, "litefs",
+				[]string{"cat", fmt.Sprintf("/litefs/%s-pos", sqliteDB.Spec.Database.Name)})
+			if err != nil {
+				// The database does not exist until the primary first writes to it
+				log.V(1).Info("Unable to read LiteFS position", "pod", pod.Name, "error", err.Error())
+			}
+			podStatus.Position = strings.TrimSpace(position)
+		}
+		status.Pods = append(status.Pods, podStatus)
+	}
+
+	return status, nil
+}
+
+// podReady returns true if the pod has the Ready condition
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
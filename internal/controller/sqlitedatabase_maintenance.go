@@ -0,0 +1,349 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+	"github.com/sqlite-operator/sqlite-operator/internal/schedule"
+)
+
+const (
+	// maintenanceContainerName is the sidecar running the maintenance tasks next to the writer
+	maintenanceContainerName = "sqlite-maintenance"
+
+	// maintenancePath holds the marker of the running task and the result of the last run of
+	// each task. It is an emptyDir, so that a task interrupted with its pod never blocks the
+	// next runs.
+	maintenancePath = "/var/run/maintenance"
+
+	// maintenanceStatusInterval is the interval between checks of a running task
+	maintenanceStatusInterval = 30 * time.Second
+)
+
+// maintenanceRunScript starts the task named by $1 running the SQL in $2 in the background,
+// unless it is already running, and records its start, end, exit code and first lines of
+// output once done. It follows the query function.
+const maintenanceRunScript = `
+dir=` + maintenancePath + `
+[ -f "$dir/$1.running" ] && exit 0
+touch "$dir/$1.running"
+(
+  start=$(date -u +%s)
+  output=$(query "$2")
+  code=$?
+  end=$(date -u +%s)
+  summary=$(printf '%s' "$output" | head -n 5 | tr '\n' ' ' | cut -c 1-512)
+  echo "$start $end $code $summary" > "$dir/$1.result.tmp"
+  mv "$dir/$1.result.tmp" "$dir/$1.result"
+  rm -f "$dir/$1.running"
+) > /dev/null 2>&1 &`
+
+// maintenanceStatusScript prints the result of the last run of each task, and the running tasks
+const maintenanceStatusScript = `cd ` + maintenancePath + ` || exit 0
+for f in *.result; do [ -f "$f" ] && echo "${f%.result} $(cat "$f")"; done
+for f in *.running; do [ -f "$f" ] && echo "${f%.running} running"; done
+true`
+
+// maintenanceTask is a maintenance task of the database with its schedule
+type maintenanceTask struct {
+	name     string
+	schedule string
+	sql      string
+}
+
+// maintenanceTasks returns the configured maintenance tasks, in the order they run when due
+// at the same time
+func maintenanceTasks(sqliteDB *databasev1alpha1.SqliteDatabase) []maintenanceTask {
+	config := sqliteDB.Spec.Maintenance
+	if config == nil {
+		return nil
+	}
+
+	var tasks []maintenanceTask
+	if task := config.IntegrityCheck; task != nil {
+		sql := "PRAGMA quick_check;"
+		if task.Full {
+			sql = "PRAGMA integrity_check;"
+		}
+		tasks = append(tasks, maintenanceTask{"integrityCheck", task.Schedule, sql})
+	}
+	if task := config.Vacuum; task != nil {
+		sql := "VACUUM;"
+		if task.Incremental {
+			sql = "PRAGMA incremental_vacuum;"
+			if task.Pages > 0 {
+				sql = fmt.Sprintf("PRAGMA incremental_vacuum(%d);", task.Pages)
+			}
+		}
+		tasks = append(tasks, maintenanceTask{"vacuum", task.Schedule, sql})
+	}
+	if task := config.Analyze; task != nil {
+		sql := "PRAGMA optimize;"
+		if task.Full {
+			sql = "ANALYZE;"
+		}
+		tasks = append(tasks, maintenanceTask{"analyze", task.Schedule, sql})
+	}
+	if task := config.Checkpoint; task != nil {
+		tasks = append(tasks, maintenanceTask{"checkpoint", task.Schedule, "PRAGMA wal_checkpoint(TRUNCATE);"})
+	}
+
+	return tasks
+}
+
+// buildMaintenanceContainer builds the sidecar in which the maintenance tasks are executed,
+// so that they open the database next to the writer and wait for its locks
+func (r *SqliteDatabaseReconciler) buildMaintenanceContainer(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.Container {
+	container := corev1.Container{
+		Name:            maintenanceContainerName,
+		Image:           r.resolveImages(sqliteDB).Sqlite,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"/bin/sh", "-c"},
+		Args:            []string{"trap 'exit 0' TERM; while true; do sleep 3600 & wait $!; done"},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "db-storage",
+				MountPath: "/var/lib/sqlite",
+			},
+			{
+				Name:      "maintenance",
+				MountPath: maintenancePath,
+			},
+		},
+	}
+	if sqliteDB.Spec.Maintenance.Resources != nil {
+		container.Resources = *sqliteDB.Spec.Maintenance.Resources
+	}
+	if sqliteDB.Spec.Database.Encryption != nil {
+		container.Env = append(container.Env, r.buildEncryptionKeyEnv(sqliteDB))
+	}
+
+	return container
+}
+
+// reconcileMaintenance records the results of the finished maintenance tasks and starts the
+// tasks due in the ready database pod. It returns the time until the next task is due or a
+// running task is checked again, zero if there is none.
+func (r *SqliteDatabaseReconciler) reconcileMaintenance(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (time.Duration, error) {
+	log := logf.FromContext(ctx)
+	tasks := maintenanceTasks(sqliteDB)
+
+	// Forget the tasks no longer configured
+	var statuses []databasev1alpha1.MaintenanceTaskStatus
+	for _, status := range sqliteDB.Status.Maintenance {
+		for _, task := range tasks {
+			if task.name == status.Task {
+				statuses = append(statuses, status)
+				break
+			}
+		}
+	}
+	sqliteDB.Status.Maintenance = statuses
+	if len(tasks) == 0 {
+		return 0, nil
+	}
+	for _, task := range tasks {
+		maintenanceTaskStatus(sqliteDB, task.name)
+	}
+
+	// The pod may run an older template without the sidecar until it is rolled out
+	var pod string
+	running := map[string]bool{}
+	if r.Executor != nil && !standbyFollowing(sqliteDB) {
+		var err error
+		if pod, err = r.getReadyPod(ctx, sqliteDB); err != nil {
+			return 0, err
+		}
+	}
+	if pod != "" {
+		output, err := r.Executor.Exec(ctx, sqliteDB.Namespace, pod, maintenanceContainerName,
+			[]string{"/bin/sh", "-c", maintenanceStatusScript})
+		if err != nil {
+			log.Info("Unable to read the results of the maintenance tasks", "pod", pod, "error", err.Error())
+			pod = ""
+		} else {
+			running = r.recordMaintenanceResults(sqliteDB, output)
+		}
+	}
+
+	now := time.Now().UTC()
+	var requeueAfter time.Duration
+	for _, task := range tasks {
+		status := maintenanceTaskStatus(sqliteDB, task.name)
+
+		// Compute the next run again when the schedule changes
+		if status.Schedule != task.schedule || status.NextRun == nil {
+			parsed, err := schedule.Parse(task.schedule)
+			if err != nil {
+				if status.Schedule != task.schedule {
+					r.recordEvent(sqliteDB, corev1.EventTypeWarning, "InvalidSchedule",
+						fmt.Sprintf("Maintenance task %s: %s", task.name, err.Error()))
+				}
+				status.Schedule = task.schedule
+				status.NextRun = nil
+				status.Message = fmt.Sprintf("Invalid schedule: %s", err.Error())
+				continue
+			}
+			status.Schedule = task.schedule
+			if next := parsed.Next(now); !next.IsZero() {
+				status.NextRun = &metav1.Time{Time: next}
+			}
+		}
+		if status.NextRun == nil {
+			continue
+		}
+
+		if running[task.name] {
+			requeueAfter = earliest(requeueAfter, maintenanceStatusInterval)
+			continue
+		}
+		if now.Before(status.NextRun.Time) {
+			requeueAfter = earliest(requeueAfter, status.NextRun.Sub(now))
+			continue
+		}
+		// A due task waits for a ready pod, and runs once however many runs were missed
+		if pod == "" {
+			requeueAfter = earliest(requeueAfter, maintenanceStatusInterval)
+			continue
+		}
+
+		script := buildQueryFunction(sqliteDB, "/var/lib/sqlite/"+sqliteDB.Spec.Database.Name,
+			sqliteDB.Spec.Maintenance.BusyTimeoutSeconds*1000) + maintenanceRunScript
+		if _, err := r.Executor.Exec(ctx, sqliteDB.Namespace, pod, maintenanceContainerName,
+			[]string{"/bin/sh", "-c", script, "sh", task.name, task.sql}); err != nil {
+			log.Info("Unable to start the maintenance task", "task", task.name, "pod", pod, "error", err.Error())
+			requeueAfter = earliest(requeueAfter, maintenanceStatusInterval)
+			continue
+		}
+		log.Info("Started maintenance task", "task", task.name, "pod", pod)
+		status.NextRun = nil
+		if parsed, _ := schedule.Parse(task.schedule); !parsed.Next(now).IsZero() {
+			status.NextRun = &metav1.Time{Time: parsed.Next(now)}
+		}
+		requeueAfter = earliest(requeueAfter, maintenanceStatusInterval)
+	}
+
+	return requeueAfter, nil
+}
+
+// recordMaintenanceResults records the results printed by the status script that are newer
+// than the last run in the status, with an Event for each. It returns the running tasks.
+func (r *SqliteDatabaseReconciler) recordMaintenanceResults(sqliteDB *databasev1alpha1.SqliteDatabase, output string) map[string]bool {
+	running := map[string]bool{}
+
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 5)
+		if len(fields) == 2 && fields[1] == "running" {
+			running[fields[0]] = true
+			continue
+		}
+		if len(fields) < 4 {
+			continue
+		}
+		start, err1 := strconv.ParseInt(fields[1], 10, 64)
+		end, err2 := strconv.ParseInt(fields[2], 10, 64)
+		code, err3 := strconv.Atoi(fields[3])
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		var message string
+		if len(fields) == 5 {
+			message = strings.TrimSpace(fields[4])
+		}
+
+		status := findMaintenanceTaskStatus(sqliteDB, fields[0])
+		lastRun := metav1.NewTime(time.Unix(start, 0))
+		if status == nil || (status.LastRun != nil && !status.LastRun.Before(&lastRun)) {
+			continue
+		}
+		duration := time.Duration(end-start) * time.Second
+		status.LastRun = &lastRun
+		status.Duration = duration.String()
+		status.Message = message
+		status.Result = "Succeeded"
+
+		if failure := maintenanceFailure(status.Task, code, message); failure != "" {
+			status.Result = "Failed"
+			r.recordEvent(sqliteDB, corev1.EventTypeWarning, "MaintenanceFailed",
+				fmt.Sprintf("Maintenance task %s failed after %s: %s", status.Task, duration, failure))
+			continue
+		}
+		r.recordEvent(sqliteDB, corev1.EventTypeNormal, "MaintenanceSucceeded",
+			fmt.Sprintf("Maintenance task %s succeeded in %s", status.Task, duration))
+	}
+
+	return running
+}
+
+// maintenanceFailure returns why the run of a task failed from its exit code and output, or
+// an empty string if it succeeded
+func maintenanceFailure(task string, code int, output string) string {
+	if code != 0 {
+		return fmt.Sprintf("exit code %d: %s", code, output)
+	}
+
+	switch task {
+	case "integrityCheck":
+		if output != "ok" {
+			return output
+		}
+	case "checkpoint":
+		// The result is busy|log|checkpointed, busy when a reader kept the WAL from being reset
+		if fields := strings.Split(output, "|"); len(fields) == 3 && fields[0] != "0" {
+			return fmt.Sprintf("checkpoint blocked by a reader, %s of %s WAL frames checkpointed", fields[2], fields[1])
+		}
+	}
+
+	return ""
+}
+
+// findMaintenanceTaskStatus returns the status of the task, or nil if it is not configured
+func findMaintenanceTaskStatus(sqliteDB *databasev1alpha1.SqliteDatabase, task string) *databasev1alpha1.MaintenanceTaskStatus {
+	for i := range sqliteDB.Status.Maintenance {
+		if sqliteDB.Status.Maintenance[i].Task == task {
+			return &sqliteDB.Status.Maintenance[i]
+		}
+	}
+	return nil
+}
+
+// maintenanceTaskStatus returns the status of the task, added if missing
+func maintenanceTaskStatus(sqliteDB *databasev1alpha1.SqliteDatabase, task string) *databasev1alpha1.MaintenanceTaskStatus {
+	if status := findMaintenanceTaskStatus(sqliteDB, task); status != nil {
+		return status
+	}
+	sqliteDB.Status.Maintenance = append(sqliteDB.Status.Maintenance, databasev1alpha1.MaintenanceTaskStatus{Task: task})
+	return &sqliteDB.Status.Maintenance[len(sqliteDB.Status.Maintenance)-1]
+}
+
+// earliest returns the shortest of two requeue intervals, ignoring zero
+func earliest(a, b time.Duration) time.Duration {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,307 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+const (
+	// maskedCondition reports whether the data source was masked when the database was created
+	maskedCondition = "Masked"
+
+	// maskingSQLEnv is the environment variable carrying the masking statements
+	maskingSQLEnv = "MASKING_SQL"
+
+	maskingNull      = "null"
+	maskingHash      = "hash"
+	maskingFakeEmail = "fakeEmail"
+	maskingFixed     = "fixed"
+	maskingDrop      = "drop"
+)
+
+// importsDataSource returns true if the database pod imports the data source itself, which
+// is left to the masking Job when the data source is masked
+func importsDataSource(sqliteDB *databasev1alpha1.SqliteDatabase) bool {
+	return sqliteDB.Spec.Database.DataSource != nil && len(sqliteDB.Spec.Database.Masking) == 0
+}
+
+// maskingJobName returns the name of the Job importing and masking the data source
+func maskingJobName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	return fmt.Sprintf("%s-masking", sqliteDB.Name)
+}
+
+// maskingRulesHash returns the SHA-256 of the masking rules as JSON, recorded once applied
+func maskingRulesHash(rules []databasev1alpha1.MaskingRule) string {
+	data, _ := json.Marshal(rules)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// reconcileMasking imports the data source and applies the masking rules in a Job before the
+// database pod first starts, so that unmasked data is never served. The hash of the applied
+// rules is recorded in status. It returns true while the Job is running or has failed.
+func (r *SqliteDatabaseReconciler) reconcileMasking(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	rules := sqliteDB.Spec.Database.Masking
+	if len(rules) == 0 {
+		return false, nil
+	}
+	hash := maskingRulesHash(rules)
+
+	condition := metav1.Condition{
+		Type:               maskedCondition,
+		Status:             metav1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+	}
+
+	if applied := sqliteDB.Status.Masking; applied != nil {
+		if applied.RulesHash == hash {
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = "Masked"
+			condition.Message = fmt.Sprintf("Data source masked with %d rules", applied.Rules)
+		} else {
+			condition.Reason = "RulesChanged"
+			condition.Message = fmt.Sprintf("Masking rules changed since the database was created with rules %s, they are only applied to new databases",
+				applied.RulesHash)
+		}
+		setCondition(sqliteDB, condition)
+		return false, nil
+	}
+
+	// Only a new database is masked, before its pod first starts
+	if workloadKind(sqliteDB) == workloadKindDeployment {
+		err := r.Get(ctx, client.ObjectKey{Name: sqliteDB.Name, Namespace: sqliteDB.Namespace}, &appsv1.Deployment{})
+		if err == nil {
+			condition.Reason = "DatabaseExists"
+			condition.Message = "The database was created before masking was configured, masking rules are only applied to new databases"
+			setCondition(sqliteDB, condition)
+			return false, nil
+		}
+		if !errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      maskingJobName(sqliteDB),
+			Namespace: sqliteDB.Namespace,
+		},
+	}
+	err := r.Get(ctx, client.ObjectKeyFromObject(job), job)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	if err == nil && job.Status.Failed > 0 {
+		// The database pod is not started on an unmasked data source
+		condition.Reason = "MaskingFailed"
+		condition.Message = fmt.Sprintf("Import or masking of the data source failed, see Job %s and delete it to retry", job.Name)
+		setCondition(sqliteDB, condition)
+		return true, nil
+	}
+
+	if job.UID == "" {
+		job = r.buildMaskingJob(sqliteDB)
+		if err := controllerutil.SetControllerReference(sqliteDB, job, r.Scheme); err != nil {
+			return false, err
+		}
+		log.Info("Masking data source", "rules", len(rules), "rulesHash", hash)
+		if err := r.Create(ctx, job); err != nil {
+			return false, err
+		}
+	}
+	if job.Status.Succeeded == 0 {
+		condition.Reason = "Masking"
+		condition.Message = fmt.Sprintf("Applying %d masking rules with Job %s", len(rules), job.Name)
+		setCondition(sqliteDB, condition)
+		return true, nil
+	}
+
+	// Record the rules before the Job goes, as the database is not masked again
+	sqliteDB.Status.Masking = &databasev1alpha1.MaskingStatus{
+		RulesHash: hash,
+		Rules:     int32(len(rules)),
+		AppliedAt: metav1.Now(),
+	}
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = "Masked"
+	condition.Message = fmt.Sprintf("Data source masked with %d rules", len(rules))
+	setCondition(sqliteDB, condition)
+	if err := r.writeStatus(ctx, sqliteDB); err != nil {
+		return false, err
+	}
+	log.Info("Data source masked", "rulesHash", hash)
+
+	return false, r.deleteOwned(ctx, sqliteDB, job)
+}
+
+// buildMaskingSQL builds the statements applying the masking rules. Deleted values are
+// overwritten and the file is vacuumed so that no unmasked data remains in free pages.
+func buildMaskingSQL(rules []databasev1alpha1.MaskingRule) string {
+	var sql strings.Builder
+	sql.WriteString("PRAGMA secure_delete = ON;\nBEGIN;\n")
+	for _, rule := range rules {
+		table := quoteSQLIdentifier(rule.Table)
+		column := quoteSQLIdentifier(rule.Column)
+		// sha3() is built into the sqlite3 shell
+		hash := fmt.Sprintf("lower(hex(sha3(CAST(%s AS TEXT), 256)))", column)
+
+		switch rule.Strategy {
+		case maskingNull:
+			fmt.Fprintf(&sql, "UPDATE %s SET %s = NULL;\n", table, column)
+		case maskingHash:
+			fmt.Fprintf(&sql, "UPDATE %s SET %s = %s WHERE %s IS NOT NULL;\n", table, column, hash, column)
+		case maskingFakeEmail:
+			fmt.Fprintf(&sql, "UPDATE %s SET %s = 'user-' || substr(%s, 1, 16) || '@example.com' WHERE %s IS NOT NULL;\n",
+				table, column, hash, column)
+		case maskingFixed:
+			fmt.Fprintf(&sql, "UPDATE %s SET %s = %s;\n", table, column, quoteSQLString(getStringValue(rule.Value, "")))
+		case maskingDrop:
+			fmt.Fprintf(&sql, "DROP TABLE IF EXISTS %s;\n", table)
+		}
+	}
+	sql.WriteString("COMMIT;\nVACUUM;\n")
+	return sql.String()
+}
+
+// quoteSQLIdentifier quotes a table or column name
+func quoteSQLIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteSQLString quotes a string literal
+func quoteSQLString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// buildMaskingJob builds the Job importing the data source next to the database file, applying
+// the masking rules to it and moving it in place, with the init containers of the database pod
+// fetching or restoring the data source
+func (r *SqliteDatabaseReconciler) buildMaskingJob(sqliteDB *databasev1alpha1.SqliteDatabase) *batchv1.Job {
+	dbPath := fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name)
+	maskPath := dbPath + ".masking"
+	apply := fmt.Sprintf(`printf '%%s\n' "$%s" | sqlite3 -bail %s > /dev/null`, maskingSQLEnv, maskPath)
+
+	script := `set -e
+mkdir -p /var/lib/sqlite`
+	if sqliteDB.Spec.Database.Encryption != nil {
+		script += fmt.Sprintf(`
+KEY_SQL="PRAGMA key = '$(printf '%%s' "$%s" | sed "s/'/''/g")';"`, encryptionKeyEnv)
+		apply = fmt.Sprintf(`{ echo "$KEY_SQL"; printf '%%s\n' "$%s"; } | sqlcipher -bail %s > /dev/null`, maskingSQLEnv, maskPath)
+	}
+	script += fmt.Sprintf(`
+if [ -f %[1]s ]; then
+  echo "Database already exists, masking rules are only applied to new databases" >&2
+  exit 1
+fi
+rm -f %[2]s %[2]s-wal %[2]s-shm`, dbPath, maskPath)
+	script += buildDataSourceImportScript(sqliteDB, maskPath)
+	script += fmt.Sprintf(`
+echo "Applying %[1]d masking rules..."
+%[2]s
+mv %[3]s %[4]s
+echo "Data source masked"`, len(sqliteDB.Spec.Database.Masking), apply, maskPath, dbPath)
+
+	container := corev1.Container{
+		Name:            "mask-data",
+		Image:           r.resolveImages(sqliteDB).Sqlite,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"/bin/sh", "-c"},
+		Args:            []string{script},
+		Env: []corev1.EnvVar{
+			{
+				Name:  maskingSQLEnv,
+				Value: buildMaskingSQL(sqliteDB.Spec.Database.Masking),
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "db-storage",
+				MountPath: "/var/lib/sqlite",
+			},
+			{
+				Name:      "data-source",
+				MountPath: dataSourcePath,
+				ReadOnly:  true,
+			},
+		},
+	}
+	if sqliteDB.Spec.Database.Encryption != nil {
+		container.Env = append(container.Env, r.buildEncryptionKeyEnv(sqliteDB))
+	}
+
+	podSpec := corev1.PodSpec{
+		RestartPolicy:    corev1.RestartPolicyNever,
+		Containers:       []corev1.Container{container},
+		ImagePullSecrets: sqliteDB.Spec.ImagePullSecrets,
+		Volumes: append([]corev1.Volume{
+			{
+				Name: "db-storage",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: deploymentClaimName(sqliteDB),
+					},
+				},
+			},
+		}, buildDataSourceVolumes(sqliteDB)...),
+	}
+	switch {
+	case sqliteDB.Spec.Database.DataSource.URL != nil:
+		podSpec.InitContainers = []corev1.Container{r.buildFetchContainer(sqliteDB)}
+	case sqliteDB.Spec.Database.DataSource.Replica != nil:
+		podSpec.InitContainers = []corev1.Container{r.buildReplicaRestoreContainer(sqliteDB)}
+	}
+	applyStorageSubPath(sqliteDB, &podSpec)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      maskingJobName(sqliteDB),
+			Namespace: sqliteDB.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "sqlite-database",
+				"app.kubernetes.io/instance":   sqliteDB.Name,
+				"app.kubernetes.io/managed-by": "sqlite-operator",
+				"app.kubernetes.io/component":  "masking",
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: ptr.To(int32(0)),
+			Template: corev1.PodTemplateSpec{
+				Spec: podSpec,
+			},
+		},
+	}
+}
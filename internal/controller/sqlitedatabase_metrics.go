@@ -0,0 +1,150 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+// databasePhases are the phases exported by the phase metric, one series each
+var databasePhases = []string{"Pending", "Running", "Standby", "Failed", "Terminating"}
+
+// reconcileErrors counts the failed stages of the reconcile of each database
+var reconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sqlite_operator_reconcile_errors_total",
+	Help: "Errors reconciling a SqliteDatabase, by stage of the reconcile.",
+}, []string{"namespace", "name", "stage"})
+
+func init() {
+	metrics.Registry.MustRegister(reconcileErrors)
+}
+
+// recordReconcileError counts an error of a stage of the reconcile, such as pvc or deployment
+func recordReconcileError(sqliteDB *databasev1alpha1.SqliteDatabase, stage string) {
+	reconcileErrors.WithLabelValues(sqliteDB.Namespace, sqliteDB.Name, stage).Inc()
+}
+
+// forgetDatabaseMetrics deletes the reconcile errors of a deleted database
+func forgetDatabaseMetrics(namespace, name string) {
+	reconcileErrors.DeletePartialMatch(prometheus.Labels{"namespace": namespace, "name": name})
+}
+
+// databaseCollector exports the state of each database from its status, read at each scrape
+// so that the series of a deleted database disappear with it
+type databaseCollector struct {
+	reader client.Reader
+
+	phase        *prometheus.Desc
+	ready        *prometheus.Desc
+	sizeBytes    *prometheus.Desc
+	walBytes     *prometheus.Desc
+	replication  *prometheus.Desc
+	lastVerified *prometheus.Desc
+}
+
+// newDatabaseCollector returns a collector listing the databases with the reader, usually the
+// cache of the manager
+func newDatabaseCollector(reader client.Reader) *databaseCollector {
+	labels := []string{"namespace", "name"}
+	return &databaseCollector{
+		reader: reader,
+		phase: prometheus.NewDesc("sqlite_operator_database_phase",
+			"Phase of the SqliteDatabase, 1 for the current phase.",
+			append(labels, "phase"), nil),
+		ready: prometheus.NewDesc("sqlite_operator_database_ready",
+			"Whether the Ready condition of the SqliteDatabase is true.",
+			labels, nil),
+		sizeBytes: prometheus.NewDesc("sqlite_operator_database_size_bytes",
+			"Size of the database file, as of the last refresh of the statistics.",
+			labels, nil),
+		walBytes: prometheus.NewDesc("sqlite_operator_database_wal_bytes",
+			"Size of the WAL, as of the last refresh of the statistics.",
+			labels, nil),
+		replication: prometheus.NewDesc("sqlite_operator_replica_seconds_since_last_replication",
+			"Seconds since the last write replicated to the Litestream replica, as of the last refresh of the statistics.",
+			append(labels, "replica"), nil),
+		lastVerified: prometheus.NewDesc("sqlite_operator_backup_last_verified_timestamp_seconds",
+			"Time the replica was last restored and passed verification.",
+			labels, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *databaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.phase
+	ch <- c.ready
+	ch <- c.sizeBytes
+	ch <- c.walBytes
+	ch <- c.replication
+	ch <- c.lastVerified
+}
+
+// Collect implements prometheus.Collector
+func (c *databaseCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	databases := &databasev1alpha1.SqliteDatabaseList{}
+	if err := c.reader.List(ctx, databases); err != nil {
+		ch <- prometheus.NewInvalidMetric(c.phase, err)
+		return
+	}
+
+	now := time.Now()
+	for _, db := range databases.Items {
+		labels := []string{db.Namespace, db.Name}
+
+		for _, phase := range databasePhases {
+			ch <- prometheus.MustNewConstMetric(c.phase, prometheus.GaugeValue,
+				boolValue(db.Status.Phase == phase), db.Namespace, db.Name, phase)
+		}
+		ch <- prometheus.MustNewConstMetric(c.ready, prometheus.GaugeValue,
+			boolValue(meta.IsStatusConditionTrue(db.Status.Conditions, "Ready")), labels...)
+
+		if stats := db.Status.Database; stats != nil {
+			ch <- prometheus.MustNewConstMetric(c.sizeBytes, prometheus.GaugeValue, float64(stats.FileBytes), labels...)
+			ch <- prometheus.MustNewConstMetric(c.walBytes, prometheus.GaugeValue, float64(stats.WALBytes), labels...)
+		}
+		for _, backup := range db.Status.Backups {
+			if backup.ReplicatedUntil == nil {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(c.replication, prometheus.GaugeValue,
+				now.Sub(backup.ReplicatedUntil.Time).Seconds(), db.Namespace, db.Name, backup.URL)
+		}
+		if db.Status.LastVerified != nil {
+			ch <- prometheus.MustNewConstMetric(c.lastVerified, prometheus.GaugeValue,
+				float64(db.Status.LastVerified.Unix()), labels...)
+		}
+	}
+}
+
+// boolValue returns 1 for true and 0 for false
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+// readReplicaName returns the name of the Deployment and Service of the read replicas
+func readReplicaName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	return fmt.Sprintf("%s-read", sqliteDB.Name)
+}
+
+// readReplicaSelector returns the labels of the read replica pods, distinct from those of
+// the database pod so that they are never selected as the writer
+func readReplicaSelector(sqliteDB *databasev1alpha1.SqliteDatabase) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":     "sqlite-database-read",
+		"app.kubernetes.io/instance": sqliteDB.Name,
+	}
+}
+
+// reconcileReadReplicas creates or updates the read replica Deployment and its Service, or
+// deletes them when read replicas are disabled
+func (r *SqliteDatabaseReconciler) reconcileReadReplicas(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      readReplicaName(sqliteDB),
+			Namespace: sqliteDB.Namespace,
+		},
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      readReplicaName(sqliteDB),
+			Namespace: sqliteDB.Namespace,
+		},
+	}
+
+	if sqliteDB.Spec.ReadReplicas == nil {
+		sqliteDB.Status.ReadReplicas = 0
+		if err := r.deleteOwned(ctx, sqliteDB, service); err != nil {
+			return err
+		}
+		return r.deleteOwned(ctx, sqliteDB, deployment)
+	}
+
+	labels := map[string]string{
+		"app.kubernetes.io/name":       "sqlite-database",
+		"app.kubernetes.io/instance":   sqliteDB.Name,
+		"app.kubernetes.io/managed-by": "sqlite-operator",
+		"app.kubernetes.io/component":  "read-replica",
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, deployment, func() error {
+		deployment.Labels = labels
+		deployment.Spec.Replicas = int32Ptr(sqliteDB.Spec.ReadReplicas.Count)
+		deployment.Spec.Selector = &metav1.LabelSelector{
+			MatchLabels: readReplicaSelector(sqliteDB),
+		}
+		deployment.Spec.Template = r.buildReadReplicaPodTemplate(sqliteDB)
+
+		return controllerutil.SetControllerReference(sqliteDB, deployment, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+	sqliteDB.Status.ReadReplicas = deployment.Status.ReadyReplicas
+
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, service, func() error {
+		service.Labels = labels
+		service.Spec.Selector = readReplicaSelector(sqliteDB)
+		service.Spec.Ports = r.buildServicePorts(sqliteDB)
+		service.Spec.Type = corev1.ServiceTypeClusterIP
+
+		return controllerutil.SetControllerReference(sqliteDB, service, r.Scheme)
+	})
+
+	return err
+}
+
+// buildReplicaRestoreFunctions returns the shell functions restoring the replica of the
+// database at replicaPath in the Litestream config to dbPath. The latest snapshot and WAL
+// segment identify the state of the replica, recorded in statePath after each restore. The
+// restore is moved over the previous file, so readers always open a consistent database.
+func buildReplicaRestoreFunctions(config, replicaPath, dbPath, statePath string) string {
+	return fmt.Sprintf(`replica_state() {
+  litestream snapshots -config %[1]s %[2]s | tail -n 1
+  litestream wal -config %[1]s %[2]s | tail -n 1
+}
+restore() {
+  state=$(replica_state) || return 1
+  rm -f %[3]s.next
+  litestream restore -config %[1]s -o %[3]s.next %[2]s || return 1
+  mv %[3]s.next %[3]s
+  echo "$state" > %[4]s
+}`, config, replicaPath, dbPath, statePath)
+}
+
+// buildReadReplicaPodTemplate builds the pod template of the read replicas. The database is
+// restored from the replica into an emptyDir before sqlite-rest starts, and restored again by
+// the follow-replica container whenever the replica has new WAL segments.
+func (r *SqliteDatabaseReconciler) buildReadReplicaPodTemplate(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.PodTemplateSpec {
+	images := r.resolveImages(sqliteDB)
+	readReplicas := sqliteDB.Spec.ReadReplicas
+	dbPath := fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name)
+	statePath := "/var/lib/sqlite/.replica-state"
+
+	restore := buildReplicaRestoreFunctions("/etc/litestream/litestream.yml", dbPath, dbPath, statePath)
+
+	restoreContainer := corev1.Container{
+		Name:            "restore-replica",
+		Image:           images.Litestream,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"/bin/sh", "-c"},
+		Args: []string{fmt.Sprintf(`%s
+until restore; do
+  echo "Waiting for the replica of %s..."
+  sleep %d
+done
+echo "Replica restored"`, restore, sqliteDB.Spec.Database.Name, readReplicas.SyncIntervalSeconds)},
+		Env: r.buildLitestreamEnv(sqliteDB),
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "db-storage",
+				MountPath: "/var/lib/sqlite",
+			},
+			{
+				Name:      "litestream-config",
+				MountPath: "/etc/litestream",
+			},
+		},
+	}
+
+	followContainer := *restoreContainer.DeepCopy()
+	followContainer.Name = "follow-replica"
+	followContainer.Args = []string{fmt.Sprintf(`%s
+while true; do
+  sleep %d
+  if [ "$(replica_state 2>/dev/null)" != "$(cat %s)" ]; then
+    restore && echo "Replica restored at $(date -u +%%Y-%%m-%%dT%%H:%%M:%%SZ)"
+  fi
+done`, restore, readReplicas.SyncIntervalSeconds, statePath)}
+
+	// sqlite-rest opens the database read-only, so the read replicas never write to it
+	dsn := fmt.Sprintf("file:%s?mode=ro", dbPath)
+	restContainer := corev1.Container{
+		Name:            "sqlite-rest",
+		Image:           images.SqliteRest,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Args:            r.buildSqliteRestArgsWithDSN(sqliteDB, dsn),
+		Ports:           r.buildSqliteRestPorts(sqliteDB),
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "db-storage",
+				MountPath: "/var/lib/sqlite",
+			},
+		},
+	}
+	if readReplicas.Resources != nil {
+		restContainer.Resources = *readReplicas.Resources
+	}
+
+	sizeLimit := resource.MustParse(sqliteDB.Spec.Database.Storage.Size)
+	volumes := []corev1.Volume{
+		{
+			Name: "db-storage",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{
+					SizeLimit: &sizeLimit,
+				},
+			},
+		},
+		{
+			Name: "litestream-config",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: fmt.Sprintf("%s-litestream-config", sqliteDB.Name),
+					},
+				},
+			},
+		},
+	}
+	if sqliteDB.Spec.SqliteRest.AuthSecret != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: "sqlite-rest-auth",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: *sqliteDB.Spec.SqliteRest.AuthSecret,
+				},
+			},
+		})
+		restContainer.VolumeMounts = append(restContainer.VolumeMounts, corev1.VolumeMount{
+			Name:      "sqlite-rest-auth",
+			MountPath: "/etc/auth",
+			ReadOnly:  true,
+		})
+	}
+
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: readReplicaSelector(sqliteDB),
+		},
+		Spec: corev1.PodSpec{
+			InitContainers:   []corev1.Container{restoreContainer},
+			Containers:       []corev1.Container{restContainer, followContainer},
+			Volumes:          volumes,
+			ImagePullSecrets: sqliteDB.Spec.ImagePullSecrets,
+		},
+	}
+	r.applyPodTemplate(sqliteDB, &template)
+
+	return template
+}
@@ -0,0 +1,278 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+const (
+	// recoveryPendingPath records a quarantined database until it is restored from the replica
+	recoveryPendingPath = "/var/lib/sqlite/.recovery-pending"
+
+	// quarantinePath holds the corrupt databases moved out of the way of the restore
+	quarantinePath = "/var/lib/sqlite/.quarantine"
+
+	// recoveredCondition reports the last restore of a corrupt database
+	recoveredCondition = "Recovered"
+)
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=delete
+
+// autoRestoreEnabled returns true if a corrupt database is restored from the replica
+func autoRestoreEnabled(sqliteDB *databasev1alpha1.SqliteDatabase) bool {
+	return sqliteDB.Spec.Recovery != nil && sqliteDB.Spec.Recovery.AutoRestore &&
+		litestreamReplicating(sqliteDB)
+}
+
+// buildCheckContainer builds the init container checking the database before it is restored
+// or opened. A corrupt database is quarantined with its WAL, so that the restore container
+// restores the replica in its place.
+func (r *SqliteDatabaseReconciler) buildCheckContainer(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.Container {
+	container := corev1.Container{
+		Name:            "check-db",
+		Image:           r.resolveImages(sqliteDB).Sqlite,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"/bin/sh", "-c"},
+		Args:            []string{buildCheckScript(sqliteDB)},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "db-storage",
+				MountPath: "/var/lib/sqlite",
+			},
+		},
+	}
+
+	return container
+}
+
+// buildCheckScript builds the script checking the database and quarantining it when corrupt.
+// Errors other than a malformed database, such as a locked one, fail the container to check
+// again.
+func buildCheckScript(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	dbPath := fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name)
+	pragma := "quick_check"
+	if sqliteDB.Spec.Recovery.IntegrityCheck == "full" {
+		pragma = "integrity_check"
+	}
+
+	return buildQueryFunction(sqliteDB, dbPath, 0) + fmt.Sprintf(`
+db=%[1]s
+if [ ! -f "$db" ]; then
+  echo "No database to check"
+  exit 0
+fi
+result=$(query "PRAGMA %[2]s;")
+code=$?
+if [ "$code" -eq 0 ] && [ "$result" = ok ]; then
+  echo "Database passed %[2]s"
+  exit 0
+fi
+if [ "$code" -ne 0 ]; then
+  case "$result" in
+  *malformed*) ;;
+  *"not a database"*) ;;
+  *)
+    echo "Unable to check the database: $result" >&2
+    exit 1
+    ;;
+  esac
+fi
+modified=$(stat -c %%Y "$db")
+if [ -f "$db-wal" ] && [ "$(stat -c %%Y "$db-wal")" -gt "$modified" ]; then
+  modified=$(stat -c %%Y "$db-wal")
+fi
+mkdir -p %[3]s
+target=%[3]s/%[4]s.$(date -u +%%Y%%m%%dT%%H%%M%%SZ)
+for suffix in "" -wal -shm; do
+  if [ -f "$db$suffix" ]; then
+    mv "$db$suffix" "$target$suffix"
+  fi
+done
+reason=$(printf '%%s' "$result" | head -n 1 | cut -c 1-256)
+{
+  echo "quarantinedAs=$target"
+  echo "detectedAt=$(date -u +%%Y-%%m-%%dT%%H:%%M:%%SZ)"
+  echo "lastModified=$(date -u -d "@$modified" +%%Y-%%m-%%dT%%H:%%M:%%SZ)"
+  echo "reason=$reason"
+} > %[5]s
+ls -1t %[3]s | grep -v -e '-wal$' -e '-shm$' | tail -n +%[6]d | while read -r old; do
+  rm -f "%[3]s/$old" "%[3]s/$old-wal" "%[3]s/$old-shm"
+done
+echo "Database is corrupt ($reason), quarantined as $target" >&2`,
+		dbPath, pragma, quarantinePath, sqliteDB.Spec.Database.Name, recoveryPendingPath,
+		sqliteDB.Spec.Recovery.KeepQuarantined+1)
+}
+
+// buildRecoveryRestoreScript builds the script of the restore container when corrupt databases
+// are restored. A quarantined database is always restored from the replica, failing while
+// the replica is unreachable rather than creating an empty database, and the end of the
+// replica is reported with the quarantine in the termination message of the container.
+func buildRecoveryRestoreScript(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	dbPath := fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name)
+
+	return fmt.Sprintf(`pending=%[1]s
+if [ ! -f "$pending" ]; then
+  exec litestream restore %[3]s -config /etc/litestream/litestream.yml %[2]s
+fi
+end=$(litestream generations -config /etc/litestream/litestream.yml %[2]s | awk 'NR > 1 { print $5 }' | sort | tail -n 1)
+rm -f %[2]s %[2]s-wal %[2]s-shm
+if ! litestream restore -config /etc/litestream/litestream.yml %[2]s; then
+  echo "Unable to restore the quarantined database from its replica" >&2
+  exit 1
+fi
+echo "replicatedUntil=$end" >> "$pending"
+cat "$pending" > /dev/termination-log
+rm -f "$pending"
+echo "Corrupt database restored from the replica"`, recoveryPendingPath, dbPath,
+		strings.Join(restoreIfEmptyFlags(sqliteDB), " "))
+}
+
+// reconcileRecovery records the restores of corrupt databases reported by the database pods,
+// and restarts the pod when a scheduled integrity check failed so that it is checked and
+// restored when it starts
+func (r *SqliteDatabaseReconciler) reconcileRecovery(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	if !autoRestoreEnabled(sqliteDB) {
+		return nil
+	}
+	if sqliteDB.Status.Recovery == nil {
+		sqliteDB.Status.Recovery = &databasev1alpha1.RecoveryStatus{}
+	}
+	status := sqliteDB.Status.Recovery
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(sqliteDB.Namespace), databasePodSelector(sqliteDB)); err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		restore := podRecoveryRestore(&pod)
+		if restore == nil || (status.LastRestore != nil && status.LastRestore.QuarantinedAs == restore.QuarantinedAs) {
+			continue
+		}
+		status.LastRestore = restore
+		message := recoveryMessage(restore)
+		r.recordEvent(sqliteDB, corev1.EventTypeWarning, "RestoredFromReplica", message)
+		setCondition(sqliteDB, metav1.Condition{
+			Type:               recoveredCondition,
+			Status:             metav1.ConditionTrue,
+			LastTransitionTime: metav1.Now(),
+			Reason:             "RestoredFromReplica",
+			Message:            message,
+		})
+	}
+
+	// Restart the pod once for each failed scheduled integrity check made since the last restore
+	check := findMaintenanceTaskStatus(sqliteDB, "integrityCheck")
+	if check == nil || check.Result != "Failed" || check.LastRun == nil {
+		return nil
+	}
+	if status.RestartedAt != nil && !status.RestartedAt.Before(check.LastRun) {
+		return nil
+	}
+	if status.LastRestore != nil && !status.LastRestore.DetectedAt.Before(check.LastRun) {
+		return nil
+	}
+	name, err := r.getReadyPod(ctx, sqliteDB)
+	if err != nil || name == "" {
+		return err
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: sqliteDB.Namespace}}
+	if err := r.Delete(ctx, pod); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	now := metav1.Now()
+	status.RestartedAt = &now
+	logf.FromContext(ctx).Info("Restarted the database pod after a failed integrity check", "pod", name)
+	r.recordEvent(sqliteDB, corev1.EventTypeWarning, "CorruptionDetected",
+		fmt.Sprintf("Integrity check failed (%s), restarted pod %s to restore the database from the replica", check.Message, name))
+
+	return nil
+}
+
+// podRecoveryRestore returns the restore reported in the termination message of the restore
+// container of the pod, or nil if the pod did not restore a corrupt database
+func podRecoveryRestore(pod *corev1.Pod) *databasev1alpha1.RecoveryRestore {
+	for _, status := range pod.Status.InitContainerStatuses {
+		terminated := status.State.Terminated
+		if status.Name != "restore-db" || terminated == nil || terminated.ExitCode != 0 {
+			continue
+		}
+
+		values := map[string]string{}
+		for _, line := range strings.Split(terminated.Message, "\n") {
+			if key, value, ok := strings.Cut(line, "="); ok {
+				values[key] = strings.TrimSpace(value)
+			}
+		}
+		if values["quarantinedAs"] == "" {
+			return nil
+		}
+
+		restore := &databasev1alpha1.RecoveryRestore{
+			QuarantinedAs:   values["quarantinedAs"],
+			Reason:          values["reason"],
+			LastModified:    parseRecoveryTime(values["lastModified"]),
+			ReplicatedUntil: parseRecoveryTime(values["replicatedUntil"]),
+		}
+		if detectedAt := parseRecoveryTime(values["detectedAt"]); detectedAt != nil {
+			restore.DetectedAt = *detectedAt
+		} else {
+			restore.DetectedAt = terminated.FinishedAt
+		}
+		return restore
+	}
+
+	return nil
+}
+
+// parseRecoveryTime parses a time of the termination message, nil if missing or invalid
+func parseRecoveryTime(value string) *metav1.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &metav1.Time{Time: t}
+}
+
+// recoveryMessage describes the restore and the writes it lost
+func recoveryMessage(restore *databasev1alpha1.RecoveryRestore) string {
+	message := fmt.Sprintf("Corrupt database (%s) quarantined as %s and restored from the replica",
+		restore.Reason, restore.QuarantinedAs)
+	switch {
+	case restore.ReplicatedUntil != nil && restore.LastModified != nil:
+		if restore.LastModified.After(restore.ReplicatedUntil.Time) {
+			message += fmt.Sprintf(", writes from %s to %s are lost",
+				restore.ReplicatedUntil.UTC().Format(time.RFC3339), restore.LastModified.UTC().Format(time.RFC3339))
+		} else {
+			message += ", no replicated write is lost"
+		}
+	case restore.ReplicatedUntil != nil:
+		message += fmt.Sprintf(", writes after %s are lost", restore.ReplicatedUntil.UTC().Format(time.RFC3339))
+	}
+	return message
+}
@@ -0,0 +1,314 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+const (
+	// DatabaseAnnotation selects the SqliteDatabase injected into an application pod
+	DatabaseAnnotation = "sqlite.io/database"
+
+	// InjectLabel opts a pod into the injection webhook. Pods without it are never sent to
+	// the webhook, so that they can still be created while the operator is unavailable.
+	InjectLabel = "sqlite.io/inject"
+
+	// InjectedAnnotation marks the pods the database has been injected into
+	InjectedAnnotation = "sqlite.io/injected"
+
+	// databaseLabel selects the application pods running a SqliteDatabase
+	databaseLabel = "sqlite.io/database"
+
+	// databasePathEnv tells the application containers where the database file is
+	databasePathEnv = "SQLITE_DATABASE_PATH"
+
+	litestreamModeSidecar = "sidecar"
+	litestreamModeExec    = "exec"
+
+	// litestreamBinPath is where the Litestream binary is copied for exec mode
+	litestreamBinPath = "/litestream"
+)
+
+// InjectSidecars adds the database volume, the init containers and the Litestream sidecar
+// of the SqliteDatabase to an application pod. The application containers get the volume
+// mounted at /var/lib/sqlite and the path of the database file in SQLITE_DATABASE_PATH.
+// In exec mode the application container runs as a child process of Litestream instead.
+// Only databases with the None workload can be injected, any other database already has a
+// writer replicating to the same replica path.
+func (r *SqliteDatabaseReconciler) InjectSidecars(sqliteDB *databasev1alpha1.SqliteDatabase, pod *corev1.Pod) error {
+	if pod.Annotations[InjectedAnnotation] == "true" {
+		return nil
+	}
+
+	switch {
+	case liteFSEnabled(sqliteDB):
+		return fmt.Errorf("SqliteDatabase %s is replicated by LiteFS and cannot be injected into application pods", sqliteDB.Name)
+	case sqliteDB.Spec.Standby != nil:
+		return fmt.Errorf("SqliteDatabase %s is a standby and cannot be injected into application pods", sqliteDB.Name)
+	case workloadKind(sqliteDB) != workloadKindNone:
+		return fmt.Errorf("SqliteDatabase %s runs its own %s writer, set workload.kind to None to inject it into application pods",
+			sqliteDB.Name, workloadKind(sqliteDB))
+	}
+
+	sqliteDB = sqliteDB.DeepCopy()
+	r.setDefaults(sqliteDB)
+	if writerLeaseEnabled(sqliteDB) && !r.NativeSidecars {
+		return fmt.Errorf("SqliteDatabase %s: %s", sqliteDB.Name, noNativeSidecarsMessage)
+	}
+	if sqliteDB.Spec.Database.Encryption != nil && r.resolveImages(sqliteDB).Sqlite == "" {
+		return fmt.Errorf("SqliteDatabase %s: %s", sqliteDB.Name, noSqlcipherImageMessage)
+	}
+	// The application takes the place of sqlite-rest
+	sqliteDB.Spec.SqliteRest = &databasev1alpha1.SqliteRestConfig{Enabled: false}
+
+	volumes := r.buildVolumes(sqliteDB)
+	if !ephemeralStorage(sqliteDB) {
+		// Claims from a volumeClaimTemplate are not in the pod volumes
+		volumes = append([]corev1.Volume{
+			{
+				Name: "db-storage",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: dbClaimName(sqliteDB),
+					},
+				},
+			},
+		}, withoutVolume(volumes, "db-storage")...)
+	}
+
+	env := []corev1.EnvVar{
+		{
+			Name:  databasePathEnv,
+			Value: fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name),
+		},
+	}
+	if sqliteDB.Spec.Database.Encryption != nil {
+		env = append(env, r.buildEncryptionKeyEnv(sqliteDB))
+	}
+
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		mounted := false
+		for _, mount := range container.VolumeMounts {
+			if mount.MountPath == "/var/lib/sqlite" {
+				mounted = true
+				break
+			}
+		}
+		if !mounted {
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name:      "db-storage",
+				MountPath: "/var/lib/sqlite",
+			})
+		}
+		container.Env = append(container.Env, env...)
+	}
+
+	initContainers := r.buildInitContainers(sqliteDB)
+	containers := r.buildContainers(sqliteDB)
+
+	// In exec mode Litestream runs the application instead of a sidecar
+	if litestreamExecMode(sqliteDB) {
+		if err := r.wrapLitestreamExec(sqliteDB, pod); err != nil {
+			return err
+		}
+		initContainers = append(withoutContainer(initContainers, "litestream"), r.buildLitestreamInstallContainer(sqliteDB))
+		containers = withoutContainer(containers, "litestream")
+		volumes = append(volumes, corev1.Volume{
+			Name: "litestream-bin",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
+	}
+
+	for _, volume := range volumes {
+		for _, existing := range pod.Spec.Volumes {
+			if existing.Name == volume.Name {
+				return fmt.Errorf("pod already has a volume named %s", volume.Name)
+			}
+		}
+	}
+
+	// The database is ready before the application init containers run
+	pod.Spec.InitContainers = append(initContainers, pod.Spec.InitContainers...)
+	pod.Spec.Containers = append(pod.Spec.Containers, containers...)
+	pod.Spec.Volumes = append(pod.Spec.Volumes, volumes...)
+	pod.Spec.ImagePullSecrets = append(pod.Spec.ImagePullSecrets, sqliteDB.Spec.ImagePullSecrets...)
+	applyStorageSubPath(sqliteDB, &pod.Spec)
+
+	// A custom service account must be granted the writer lease Role by the user
+	if writerLeaseEnabled(sqliteDB) && (pod.Spec.ServiceAccountName == "" || pod.Spec.ServiceAccountName == "default") {
+		pod.Spec.ServiceAccountName = serviceAccountName(sqliteDB)
+	}
+
+	if pod.Labels == nil {
+		pod.Labels = make(map[string]string)
+	}
+	pod.Labels[databaseLabel] = sqliteDB.Name
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[InjectedAnnotation] = "true"
+
+	return nil
+}
+
+// litestreamReplicating returns true if Litestream replicates the database. A standby only
+// replicates to its own Litestream replicas once promoted.
+func litestreamReplicating(sqliteDB *databasev1alpha1.SqliteDatabase) bool {
+	return sqliteDB.Spec.Litestream != nil && sqliteDB.Spec.Litestream.Enabled && !standbyFollowing(sqliteDB)
+}
+
+// litestreamExecMode returns true if Litestream runs the application container
+func litestreamExecMode(sqliteDB *databasev1alpha1.SqliteDatabase) bool {
+	return litestreamReplicating(sqliteDB) &&
+		sqliteDB.Spec.Litestream.Mode == litestreamModeExec
+}
+
+// wrapLitestreamExec makes the application container run its command as a child process of
+// Litestream, which replicates from before the application starts until after it exits
+func (r *SqliteDatabaseReconciler) wrapLitestreamExec(sqliteDB *databasev1alpha1.SqliteDatabase, pod *corev1.Pod) error {
+	exec := sqliteDB.Spec.Litestream.Exec
+	if exec == nil {
+		exec = &databasev1alpha1.LitestreamExecConfig{}
+	}
+
+	var container *corev1.Container
+	for i := range pod.Spec.Containers {
+		if exec.Container == "" || pod.Spec.Containers[i].Name == exec.Container {
+			container = &pod.Spec.Containers[i]
+			break
+		}
+	}
+	if container == nil {
+		return fmt.Errorf("pod has no container named %s to run with Litestream", exec.Container)
+	}
+
+	command := exec.Command
+	if len(command) == 0 {
+		command = append(append([]string{}, container.Command...), container.Args...)
+	}
+	if len(command) == 0 {
+		return fmt.Errorf("container %s sets no command, set litestream.exec.command to run it with Litestream", container.Name)
+	}
+
+	container.Command = []string{litestreamBinPath + "/litestream"}
+	container.Args = []string{
+		"replicate", "-config", "/etc/litestream/litestream.yml",
+		"-exec", shellJoin(command),
+	}
+	container.Env = append(container.Env, r.buildLitestreamEnv(sqliteDB)...)
+	container.VolumeMounts = append(container.VolumeMounts,
+		corev1.VolumeMount{
+			Name:      "litestream-config",
+			MountPath: "/etc/litestream",
+		},
+		corev1.VolumeMount{
+			Name:      "litestream-bin",
+			MountPath: litestreamBinPath,
+			ReadOnly:  true,
+		},
+	)
+
+	return nil
+}
+
+// buildLitestreamInstallContainer builds the init container copying the static Litestream
+// binary into the pod, so that exec mode works with any application image
+func (r *SqliteDatabaseReconciler) buildLitestreamInstallContainer(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.Container {
+	return corev1.Container{
+		Name:            "install-litestream",
+		Image:           r.resolveImages(sqliteDB).Litestream,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"cp", "/usr/local/bin/litestream", litestreamBinPath + "/litestream"},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "litestream-bin",
+				MountPath: litestreamBinPath,
+			},
+		},
+	}
+}
+
+// withoutContainer returns the containers without the one with the given name
+func withoutContainer(containers []corev1.Container, name string) []corev1.Container {
+	var result []corev1.Container
+	for _, container := range containers {
+		if container.Name != name {
+			result = append(result, container)
+		}
+	}
+	return result
+}
+
+// withoutVolume returns the volumes without the one with the given name
+func withoutVolume(volumes []corev1.Volume, name string) []corev1.Volume {
+	var result []corev1.Volume
+	for _, volume := range volumes {
+		if volume.Name != name {
+			result = append(result, volume)
+		}
+	}
+	return result
+}
+
+// shellJoin quotes a command for Litestream, which splits -exec with shell word rules
+func shellJoin(command []string) string {
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes a word for the shell, leaving words without special characters as is
+func shellQuote(word string) string {
+	if word != "" && strings.Trim(word, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,@%+") == "" {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+// getReadyInjectedPods returns the number of ready application pods the database has been injected into
+func (r *SqliteDatabaseReconciler) getReadyInjectedPods(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (int32, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(sqliteDB.Namespace), client.MatchingLabels{
+		databaseLabel: sqliteDB.Name,
+	}); err != nil {
+		return 0, err
+	}
+
+	var ready int32
+	for _, pod := range pods.Items {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				ready++
+			}
+		}
+	}
+
+	return ready, nil
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+const (
+	// standbyStatePath records the state of the replica last restored by a standby
+	standbyStatePath = "/var/lib/sqlite/.standby-state"
+
+	// standbySyncedPath records the Unix time a standby was last found in sync with the replica
+	standbySyncedPath = "/var/lib/sqlite/.standby-synced"
+
+	// standbyPromotedPath marks a database promoted from a standby, so that the final restore
+	// only runs once
+	standbyPromotedPath = "/var/lib/sqlite/.standby-promoted"
+
+	// standbyStatusInterval is the interval between measures of the lag of a standby
+	standbyStatusInterval = 30 * time.Second
+)
+
+// standbyFollowing returns true if the database is a standby following the replica of its
+// source. A promoted database never follows its source again.
+func standbyFollowing(sqliteDB *databasev1alpha1.SqliteDatabase) bool {
+	return sqliteDB.Spec.Standby != nil && !sqliteDB.Spec.Standby.Promote && !standbyPromoted(sqliteDB)
+}
+
+// standbyPromoted returns true once the promoted database is running as a primary
+func standbyPromoted(sqliteDB *databasev1alpha1.SqliteDatabase) bool {
+	return sqliteDB.Status.Standby != nil && sqliteDB.Status.Standby.PromotedAt != nil
+}
+
+// standbyConfigName returns the name of the ConfigMap holding the Litestream configuration
+// of the source of the standby
+func standbyConfigName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	return fmt.Sprintf("%s-standby", sqliteDB.Name)
+}
+
+// reconcileStandbyConfig creates or updates the ConfigMap with the Litestream configuration
+// of the source of the standby
+func (r *SqliteDatabaseReconciler) reconcileStandbyConfig(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      standbyConfigName(sqliteDB),
+			Namespace: sqliteDB.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "sqlite-database",
+				"app.kubernetes.io/instance":   sqliteDB.Name,
+				"app.kubernetes.io/managed-by": "sqlite-operator",
+			},
+		},
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		configMap.Data = map[string]string{
+			"litestream.yml": r.buildLitestreamConfig(replicaSourceDatabase(sqliteDB, &sqliteDB.Spec.Standby.FromReplica)),
+		}
+		return controllerutil.SetControllerReference(sqliteDB, configMap, r.Scheme)
+	})
+
+	return err
+}
+
+// buildStandbyContainer builds a container restoring the replica of the source of the standby
+// with the script, after the restore functions
+func (r *SqliteDatabaseReconciler) buildStandbyContainer(sqliteDB *databasev1alpha1.SqliteDatabase, name, script string) corev1.Container {
+	fromReplica := &sqliteDB.Spec.Standby.FromReplica
+	restore := buildReplicaRestoreFunctions("/etc/litestream-standby/litestream.yml",
+		fmt.Sprintf("/var/lib/sqlite/%s", fromReplica.Database),
+		fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name),
+		standbyStatePath)
+
+	return corev1.Container{
+		Name:            name,
+		Image:           r.resolveImages(sqliteDB).Litestream,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"/bin/sh", "-c"},
+		Args:            []string{restore + "\n" + script},
+		Env:             r.buildLitestreamEnv(replicaSourceDatabase(sqliteDB, fromReplica)),
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "db-storage",
+				MountPath: "/var/lib/sqlite",
+			},
+			{
+				Name:      "standby-config",
+				MountPath: "/etc/litestream-standby",
+			},
+		},
+	}
+}
+
+// buildStandbyInitContainer builds the init container restoring the replica before the
+// database is served. A standby keeps serving the database it last restored while the
+// replica is unreachable. A promoted standby restores the replica one last time.
+func (r *SqliteDatabaseReconciler) buildStandbyInitContainer(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.Container {
+	dbPath := fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name)
+	interval := sqliteDB.Spec.Standby.SyncIntervalSeconds
+
+	if !standbyFollowing(sqliteDB) {
+		return r.buildStandbyContainer(sqliteDB, "promote-standby", fmt.Sprintf(`if [ -f %[1]s ]; then
+  echo "Standby already promoted"
+  exit 0
+fi
+until restore; do
+  echo "Waiting for the replica of %[2]s..."
+  sleep %[3]d
+done
+rm -f %[4]s-wal %[4]s-shm %[5]s %[6]s
+touch %[1]s
+echo "Standby promoted"`, standbyPromotedPath, sqliteDB.Spec.Standby.FromReplica.Database, interval,
+			dbPath, standbyStatePath, standbySyncedPath))
+	}
+
+	return r.buildStandbyContainer(sqliteDB, "restore-standby", fmt.Sprintf(`until restore; do
+  if [ -f %[1]s ]; then
+    echo "Replica of %[2]s unreachable, serving the last restored database"
+    exit 0
+  fi
+  echo "Waiting for the replica of %[2]s..."
+  sleep %[3]d
+done
+date -u +%%s > %[4]s
+echo "Replica restored"`, dbPath, sqliteDB.Spec.Standby.FromReplica.Database, interval, standbySyncedPath))
+}
+
+// buildStandbyFollowContainer builds the container restoring the replica again whenever it
+// has new WAL segments, and recording when the standby was last in sync with it
+func (r *SqliteDatabaseReconciler) buildStandbyFollowContainer(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.Container {
+	return r.buildStandbyContainer(sqliteDB, "follow-standby", fmt.Sprintf(`while true; do
+  sleep %d
+  if [ "$(replica_state 2>/dev/null)" = "$(cat %s 2>/dev/null)" ] || restore; then
+    date -u +%%s > %s
+  fi
+done`, sqliteDB.Spec.Standby.SyncIntervalSeconds, standbyStatePath, standbySyncedPath))
+}
+
+// updateStandbyStatus reports a following standby as such with its lag, and records the
+// promotion once the promoted pod is running
+func (r *SqliteDatabaseReconciler) updateStandbyStatus(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	// The promotion is kept once the standby configuration is removed
+	if sqliteDB.Spec.Standby == nil || standbyPromoted(sqliteDB) {
+		return nil
+	}
+	if sqliteDB.Status.Standby == nil {
+		sqliteDB.Status.Standby = &databasev1alpha1.StandbyStatus{}
+	}
+	status := sqliteDB.Status.Standby
+
+	if !standbyFollowing(sqliteDB) {
+		if sqliteDB.Status.Phase != "Running" {
+			sqliteDB.Status.Message = fmt.Sprintf("Promoting standby after a final restore of the replica of %s",
+				sqliteDB.Spec.Standby.FromReplica.Database)
+			return nil
+		}
+		// The ready pod may still be the standby until the promoted one is rolled out
+		rolledOut, err := r.workloadRolledOut(ctx, sqliteDB)
+		if err != nil || !rolledOut {
+			sqliteDB.Status.Phase = "Pending"
+			sqliteDB.Status.Message = "Promoting standby"
+			return err
+		}
+		now := metav1.Now()
+		status.PromotedAt = &now
+		status.Lag = ""
+		r.recordEvent(sqliteDB, corev1.EventTypeNormal, "Promoted", "Standby promoted to a primary")
+		return nil
+	}
+
+	if sqliteDB.Status.Phase != "Running" {
+		return nil
+	}
+	sqliteDB.Status.Phase = "Standby"
+	sqliteDB.Status.Message = fmt.Sprintf("Following the replica of %s", sqliteDB.Spec.Standby.FromReplica.Database)
+
+	if r.Executor == nil {
+		return nil
+	}
+	pod, err := r.getReadyPod(ctx, sqliteDB)
+	if err != nil || pod == "" {
+		return err
+	}
+	output, err := r.Executor.Exec(ctx, sqliteDB.Namespace, pod, "follow-standby", []string{"cat", standbySyncedPath})
+	if err != nil {
+		logf.FromContext(ctx).Info("Unable to read the last sync of the standby", "pod", pod, "error", err.Error())
+		return nil
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return nil
+	}
+	lastSync := metav1.NewTime(time.Unix(seconds, 0))
+	status.LastSyncTime = &lastSync
+	status.Lag = time.Since(lastSync.Time).Round(time.Second).String()
+
+	return nil
+}
+
+// getReadyPod returns the name of a ready database pod, or an empty string if there is none
+func (r *SqliteDatabaseReconciler) getReadyPod(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (string, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(sqliteDB.Namespace), client.MatchingLabels{
+		"app.kubernetes.io/name":     "sqlite-database",
+		"app.kubernetes.io/instance": sqliteDB.Name,
+	}); err != nil {
+		return "", err
+	}
+	for i := range pods.Items {
+		if pods.Items[i].DeletionTimestamp == nil && podReady(&pods.Items[i]) {
+			return pods.Items[i].Name, nil
+		}
+	}
+	return "", nil
+}
+
+// workloadRolledOut returns true once the pods of the workload run its current template
+func (r *SqliteDatabaseReconciler) workloadRolledOut(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (bool, error) {
+	key := types.NamespacedName{Name: sqliteDB.Name, Namespace: sqliteDB.Namespace}
+
+	if workloadKind(sqliteDB) == workloadKindStatefulSet {
+		statefulSet := &appsv1.StatefulSet{}
+		if err := r.Get(ctx, key, statefulSet); err != nil {
+			return false, err
+		}
+		return statefulSet.Status.ObservedGeneration >= statefulSet.Generation &&
+			statefulSet.Status.UpdateRevision == statefulSet.Status.CurrentRevision &&
+			statefulSet.Status.ReadyReplicas > 0, nil
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(ctx, key, deployment); err != nil {
+		return false, err
+	}
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas == deployment.Status.Replicas &&
+		deployment.Status.ReadyReplicas > 0, nil
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+// statisticsBusyTimeout is the time in milliseconds the PRAGMAs of the statistics wait for
+// the locks of the writer
+const statisticsBusyTimeout = 5000
+
+// reconcileStatistics refreshes the statistics of the database and the last backup once the
+// interval elapsed, from the agent when it runs and otherwise through pods/exec. It returns
+// the time until the next refresh, zero if the statistics cannot be collected.
+func (r *SqliteDatabaseReconciler) reconcileStatistics(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (time.Duration, error) {
+	log := logf.FromContext(ctx)
+
+	useAgent := agentEnabled(sqliteDB) && r.Agent != nil
+	if sqliteDB.Spec.Statistics == nil || liteFSEnabled(sqliteDB) || (!useAgent && r.Executor == nil) {
+		return 0, nil
+	}
+	interval := time.Duration(sqliteDB.Spec.Statistics.IntervalSeconds) * time.Second
+	if status := sqliteDB.Status.Database; status != nil && status.UpdatedAt != nil {
+		if elapsed := time.Since(status.UpdatedAt.Time); elapsed < interval {
+			return interval - elapsed, nil
+		}
+	}
+
+	pod, err := r.getRunningPod(ctx, sqliteDB)
+	if err != nil || pod == nil {
+		return interval, err
+	}
+
+	var stats *databasev1alpha1.DatabaseStatus
+	if useAgent && pod.Status.PodIP != "" && containerRunning(pod, agentContainerName) {
+		stats, err = r.agentStatistics(ctx, sqliteDB, pod)
+	} else if r.Executor != nil {
+		stats, err = r.execStatistics(ctx, sqliteDB, pod)
+	}
+	if err != nil {
+		log.Info("Unable to collect the statistics of the database", "pod", pod.Name, "error", err.Error())
+		return interval, nil
+	}
+	if stats != nil {
+		now := metav1.Now()
+		stats.Pod = pod.Name
+		stats.UpdatedAt = &now
+		sqliteDB.Status.Database = stats
+	}
+
+	// Read the end of the replicas where Litestream runs
+	if !litestreamReplicating(sqliteDB) {
+		sqliteDB.Status.Backups = nil
+	} else if r.Executor != nil && len(sqliteDB.Spec.Litestream.Replicas) > 0 && containerRunning(pod, "litestream") {
+		replicated, err := r.replicatedUntil(ctx, sqliteDB, pod)
+		if err != nil {
+			log.Info("Unable to read the end of the replicas", "pod", pod.Name, "error", err.Error())
+			return interval, nil
+		}
+		backups := make([]databasev1alpha1.ReplicaBackupStatus, 0, len(replicated))
+		for i, t := range replicated {
+			url := r.buildReplicaURL(sqliteDB.Spec.Litestream.Replicas[i])
+			if findReplicaBackup(backups, url) == nil {
+				backups = append(backups, databasev1alpha1.ReplicaBackupStatus{URL: url, ReplicatedUntil: t})
+			}
+			if t != nil && (sqliteDB.Status.LastBackup == nil || t.After(sqliteDB.Status.LastBackup.Time)) {
+				sqliteDB.Status.LastBackup = t
+			}
+		}
+		sqliteDB.Status.Backups = backups
+	}
+
+	return interval, nil
+}
+
+// agentStatistics returns the statistics served by the agent of the pod
+func (r *SqliteDatabaseReconciler) agentStatistics(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase, pod *corev1.Pod) (*databasev1alpha1.DatabaseStatus, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: agentSecretName(sqliteDB), Namespace: sqliteDB.Namespace}, secret); err != nil {
+		return nil, err
+	}
+	endpoint := "http://" + net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(sqliteDB.Spec.Agent.Port)))
+	stats, err := r.Agent.Stats(ctx, endpoint, string(secret.Data[agentTokenKey]))
+	if err != nil {
+		return nil, err
+	}
+
+	status := &databasev1alpha1.DatabaseStatus{
+		Size:          formatBytes(stats.DatabaseBytes + stats.WALBytes),
+		FileBytes:     stats.DatabaseBytes,
+		WALBytes:      stats.WALBytes,
+		PageSize:      stats.PageSize,
+		PageCount:     stats.PageCount,
+		FreelistCount: stats.FreelistCount,
+		SchemaVersion: stats.UserVersion,
+		TableCount:    int32(len(stats.Tables)),
+	}
+	if !stats.LastModified.IsZero() {
+		status.LastModified = &metav1.Time{Time: stats.LastModified}
+	}
+	return status, nil
+}
+
+// execStatistics returns the statistics of the database measured in a running container of
+// the pod. The PRAGMAs are only read in a container with the shell of the database, such as
+// the maintenance sidecar.
+func (r *SqliteDatabaseReconciler) execStatistics(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase, pod *corev1.Pod) (*databasev1alpha1.DatabaseStatus, error) {
+	container := maintenanceContainerName
+	if !containerRunning(pod, container) {
+		container = databaseContainer(pod)
+	}
+	if container == "" {
+		return nil, fmt.Errorf("pod %s has no running container mounting the database", pod.Name)
+	}
+
+	output, err := r.Executor.Exec(ctx, pod.Namespace, pod.Name, container,
+		[]string{"/bin/sh", "-c", buildStatisticsScript(sqliteDB)})
+	if err != nil {
+		return nil, err
+	}
+	return parseStatistics(output)
+}
+
+// buildStatisticsScript builds the script printing the size and modification time of the
+// database file and its WAL, and the PRAGMAs when the shell of the database is installed
+func buildStatisticsScript(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	dbPath := fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name)
+	shell := "sqlite3"
+	if sqliteDB.Spec.Database.Encryption != nil {
+		shell = "sqlcipher"
+	}
+
+	return buildQueryFunction(sqliteDB, dbPath, statisticsBusyTimeout) + fmt.Sprintf(`
+db=%[1]s
+[ -f "$db" ] || exit 0
+echo "file $(stat -c '%%s %%Y' "$db")"
+[ -f "$db-wal" ] && echo "wal $(stat -c '%%s %%Y' "$db-wal")"
+if command -v %[2]s > /dev/null 2>&1; then
+  echo "pragmas $(query "PRAGMA page_size; PRAGMA page_count; PRAGMA freelist_count; PRAGMA user_version; SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite\_%%' ESCAPE '\';" | tr '\n' ' ')"
+fi
+true`, dbPath, shell)
+}
+
+// parseStatistics parses the output of the statistics script. PRAGMAs that failed, such as
+// on a locked database, are left out.
+func parseStatistics(output string) (*databasev1alpha1.DatabaseStatus, error) {
+	status := &databasev1alpha1.DatabaseStatus{}
+	var lastModified int64
+
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		values := make([]int64, 0, len(fields)-1)
+		for _, field := range fields[1:] {
+			value, err := strconv.ParseInt(field, 10, 64)
+			if err != nil {
+				break
+			}
+			values = append(values, value)
+		}
+
+		switch {
+		case (fields[0] == "file" || fields[0] == "wal") && len(values) == 2:
+			if fields[0] == "file" {
+				status.FileBytes = values[0]
+			} else {
+				status.WALBytes = values[0]
+			}
+			lastModified = max(lastModified, values[1])
+		case fields[0] == "pragmas" && len(values) == 5:
+			status.PageSize, status.PageCount, status.FreelistCount, status.SchemaVersion =
+				values[0], values[1], values[2], values[3]
+			status.TableCount = int32(values[4])
+		case fields[0] == "pragmas":
+		default:
+			return nil, fmt.Errorf("unexpected statistics %q", line)
+		}
+	}
+
+	status.Size = formatBytes(status.FileBytes + status.WALBytes)
+	if lastModified > 0 {
+		status.LastModified = &metav1.Time{Time: time.Unix(lastModified, 0).UTC()}
+	}
+	return status, nil
+}
+
+// replicatedUntil returns the end of the latest generation of each replica, listed by
+// Litestream in its container with a configuration of the replica alone, nil for a replica
+// without generation
+func (r *SqliteDatabaseReconciler) replicatedUntil(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase, pod *corev1.Pod) ([]*metav1.Time, error) {
+	dbPath := fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name)
+
+	var script strings.Builder
+	for i, replica := range sqliteDB.Spec.Litestream.Replicas {
+		config := r.buildLitestreamConfig(replicaSourceDatabase(sqliteDB, &databasev1alpha1.ReplicaDataSource{
+			Replicas: []databasev1alpha1.ReplicaConfig{replica},
+			Database: sqliteDB.Spec.Database.Name,
+		}))
+		fmt.Fprintf(&script, `cat > /tmp/litestream-replica.yml <<'EOF'
+%[1]sEOF
+echo "%[2]d $(litestream generations -config /tmp/litestream-replica.yml %[3]s 2>/dev/null | awk 'NR > 1 { print $5 }' | sort | tail -n 1)"
+`, config, i, dbPath)
+	}
+	script.WriteString("rm -f /tmp/litestream-replica.yml\n")
+
+	output, err := r.Executor.Exec(ctx, pod.Namespace, pod.Name, "litestream", []string{"/bin/sh", "-c", script.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	replicated := make([]*metav1.Time, len(sqliteDB.Spec.Litestream.Replicas))
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		i, err := strconv.Atoi(fields[0])
+		if err != nil || i < 0 || i >= len(replicated) {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, fields[1]); err == nil {
+			replicated[i] = &metav1.Time{Time: t}
+		}
+	}
+	return replicated, nil
+}
+
+// findReplicaBackup returns the backup of the replica with the URL, nil if there is none
+func findReplicaBackup(backups []databasev1alpha1.ReplicaBackupStatus, url string) *databasev1alpha1.ReplicaBackupStatus {
+	for i := range backups {
+		if backups[i].URL == url {
+			return &backups[i]
+		}
+	}
+	return nil
+}
+
+// formatBytes formats a size with binary units, such as 1.5Mi
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return strconv.FormatInt(size, 10)
+	}
+	value, suffix := float64(size), ""
+	for _, s := range []string{"Ki", "Mi", "Gi", "Ti", "Pi"} {
+		if value < unit {
+			break
+		}
+		value /= unit
+		suffix = s
+	}
+	return strings.TrimSuffix(strconv.FormatFloat(value, 'f', 1, 64), ".0") + suffix
+}
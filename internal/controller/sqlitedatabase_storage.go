@@ -0,0 +1,504 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+const (
+	// storageResizedCondition reports whether the database volume has the requested size
+	storageResizedCondition = "StorageResized"
+
+	// autoGrowInterval is how often the database files are measured when autoGrow is enabled
+	autoGrowInterval = time.Minute
+
+	// maxStorageHistory is the number of expansions kept in the status
+	maxStorageHistory = 10
+)
+
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
+
+// reconcileStorageSize expands the database claim to the requested size and reports its capacity.
+// Volumes cannot shrink, so a smaller size is refused.
+func (r *SqliteDatabaseReconciler) reconcileStorageSize(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	log := logf.FromContext(ctx)
+
+	if ephemeralStorage(sqliteDB) {
+		sqliteDB.Status.Storage = nil
+		meta.RemoveStatusCondition(&sqliteDB.Status.Conditions, storageResizedCondition)
+		return nil
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: dbClaimName(sqliteDB), Namespace: sqliteDB.Namespace}, pvc)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The claim of a StatefulSet is created with its pod
+			return nil
+		}
+		return err
+	}
+
+	// Sizes reached by autoGrow are kept when the spec is smaller
+	desired := resource.MustParse(sqliteDB.Spec.Database.Storage.Size)
+	if grown := autoGrownSize(sqliteDB); grown != nil && grown.Cmp(desired) > 0 {
+		desired = *grown
+	}
+	// An existing claim may be larger than the spec
+	if sqliteDB.Spec.Database.Storage.ExistingClaim != "" {
+		if requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; requested.Cmp(desired) > 0 {
+			desired = requested
+		}
+	}
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+
+	condition := metav1.Condition{
+		Type:               storageResizedCondition,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             "Resized",
+		Message:            fmt.Sprintf("Volume capacity is %s", capacity.String()),
+	}
+
+	switch {
+	case desired.Cmp(requested) < 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ShrinkRefused"
+		condition.Message = fmt.Sprintf("Requested size %s is smaller than the volume size %s, volumes cannot shrink",
+			desired.String(), requested.String())
+	case desired.Cmp(requested) > 0:
+		allowed, err := r.storageClassAllowsExpansion(ctx, pvc)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "ExpansionNotSupported"
+			condition.Message = fmt.Sprintf("StorageClass %q does not allow volume expansion",
+				getStringValue(pvc.Spec.StorageClassName, ""))
+			break
+		}
+
+		log.Info("Expanding database volume", "claim", pvc.Name, "from", requested.String(), "to", desired.String())
+		pvc.Spec.Resources.Requests[corev1.ResourceStorage] = desired
+		if err := r.Update(ctx, pvc); err != nil {
+			return err
+		}
+		requested = desired
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Resizing"
+		condition.Message = fmt.Sprintf("Expanding volume to %s", desired.String())
+	case capacity.IsZero():
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Pending"
+		condition.Message = "Waiting for the volume to be bound"
+	case capacity.Cmp(requested) < 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Resizing"
+		condition.Message = fmt.Sprintf("Expanding volume to %s", requested.String())
+		for _, c := range pvc.Status.Conditions {
+			if c.Type == corev1.PersistentVolumeClaimFileSystemResizePending && c.Status == corev1.ConditionTrue {
+				condition.Reason = "FileSystemResizePending"
+				condition.Message = "Volume has been expanded, waiting for the file system to be resized on the node"
+			}
+		}
+	}
+
+	if sqliteDB.Status.Storage == nil {
+		sqliteDB.Status.Storage = &databasev1alpha1.StorageStatus{}
+	}
+	sqliteDB.Status.Storage.Requested = requested.String()
+	sqliteDB.Status.Storage.Capacity = ""
+	if !capacity.IsZero() {
+		sqliteDB.Status.Storage.Capacity = capacity.String()
+	}
+	setCondition(sqliteDB, condition)
+
+	return nil
+}
+
+// storageClassAllowsExpansion returns true if the StorageClass of the claim allows volume expansion
+func (r *SqliteDatabaseReconciler) storageClassAllowsExpansion(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (bool, error) {
+	name := getStringValue(pvc.Spec.StorageClassName, "")
+	if name == "" {
+		return false, nil
+	}
+
+	storageClass := &storagev1.StorageClass{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name}, storageClass); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return storageClass.AllowVolumeExpansion != nil && *storageClass.AllowVolumeExpansion, nil
+}
+
+// autoGrowEnabled returns true if the database volume grows automatically
+func autoGrowEnabled(sqliteDB *databasev1alpha1.SqliteDatabase) bool {
+	return sqliteDB.Spec.Database.Storage.AutoGrow != nil && sqliteDB.Spec.Database.Storage.AutoGrow.Enabled &&
+		!ephemeralStorage(sqliteDB)
+}
+
+// autoGrownSize returns the size of the last expansion made by autoGrow, if any
+func autoGrownSize(sqliteDB *databasev1alpha1.SqliteDatabase) *resource.Quantity {
+	if sqliteDB.Status.Storage == nil || len(sqliteDB.Status.Storage.History) == 0 {
+		return nil
+	}
+	size, err := resource.ParseQuantity(sqliteDB.Status.Storage.History[len(sqliteDB.Status.Storage.History)-1].To)
+	if err != nil {
+		return nil
+	}
+	return &size
+}
+
+// reconcileAutoGrow measures the database file and its WAL in the running pod and expands the
+// claim by the configured increment once they use more than the threshold of its capacity.
+// Every expansion is recorded as an Event and in the status history.
+func (r *SqliteDatabaseReconciler) reconcileAutoGrow(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	log := logf.FromContext(ctx)
+
+	if !autoGrowEnabled(sqliteDB) || r.Executor == nil {
+		if sqliteDB.Status.Storage != nil {
+			sqliteDB.Status.Storage.Used = ""
+		}
+		return nil
+	}
+	autoGrow := sqliteDB.Spec.Database.Storage.AutoGrow
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: dbClaimName(sqliteDB), Namespace: sqliteDB.Namespace}, pvc)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	used, err := r.measureDatabase(ctx, sqliteDB)
+	if err != nil {
+		// The pod may be restarting, the files are measured again on the next interval
+		log.Info("Could not measure database files", "error", err.Error())
+		return nil
+	}
+	if used == nil {
+		return nil
+	}
+	if sqliteDB.Status.Storage == nil {
+		sqliteDB.Status.Storage = &databasev1alpha1.StorageStatus{}
+	}
+	sqliteDB.Status.Storage.Used = used.String()
+
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+	if capacity.IsZero() || capacity.Cmp(requested) < 0 {
+		// Wait for the previous expansion to complete
+		return nil
+	}
+	if used.Value()*100 < capacity.Value()*int64(autoGrow.ThresholdPercent) {
+		return nil
+	}
+
+	maxSize := resource.MustParse(autoGrow.MaxSize)
+	if requested.Cmp(maxSize) >= 0 {
+		r.recordEvent(sqliteDB, corev1.EventTypeWarning, "AutoGrowLimitReached",
+			fmt.Sprintf("Database uses %s of %s and the volume has reached the autoGrow maximum size %s",
+				used.String(), capacity.String(), maxSize.String()))
+		return nil
+	}
+
+	allowed, err := r.storageClassAllowsExpansion(ctx, pvc)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		r.recordEvent(sqliteDB, corev1.EventTypeWarning, "AutoGrowNotSupported",
+			fmt.Sprintf("StorageClass %q does not allow volume expansion", getStringValue(pvc.Spec.StorageClassName, "")))
+		return nil
+	}
+
+	target := requested.DeepCopy()
+	target.Add(resource.MustParse(autoGrow.Increment))
+	if target.Cmp(maxSize) > 0 {
+		target = maxSize
+	}
+
+	log.Info("Growing database volume", "claim", pvc.Name, "used", used.String(), "from", requested.String(), "to", target.String())
+	pvc.Spec.Resources.Requests[corev1.ResourceStorage] = target
+	if err := r.Update(ctx, pvc); err != nil {
+		return err
+	}
+
+	sqliteDB.Status.Storage.History = append(sqliteDB.Status.Storage.History, databasev1alpha1.StorageGrowth{
+		Time: metav1.Now(),
+		From: requested.String(),
+		To:   target.String(),
+		Used: used.String(),
+	})
+	if len(sqliteDB.Status.Storage.History) > maxStorageHistory {
+		sqliteDB.Status.Storage.History = sqliteDB.Status.Storage.History[len(sqliteDB.Status.Storage.History)-maxStorageHistory:]
+	}
+	r.recordEvent(sqliteDB, corev1.EventTypeNormal, "StorageGrown",
+		fmt.Sprintf("Database uses %s of %s, growing volume from %s to %s",
+			used.String(), capacity.String(), requested.String(), target.String()))
+
+	return nil
+}
+
+// databasePodSelector selects the pods mounting the database, the pods of the workload or
+// the pods the sidecars are injected in
+func databasePodSelector(sqliteDB *databasev1alpha1.SqliteDatabase) client.MatchingLabels {
+	if workloadKind(sqliteDB) == workloadKindNone {
+		return client.MatchingLabels{databaseLabel: sqliteDB.Name}
+	}
+	return client.MatchingLabels{
+		"app.kubernetes.io/name":     "sqlite-database",
+		"app.kubernetes.io/instance": sqliteDB.Name,
+	}
+}
+
+// measureDatabase returns the size of the database file and its WAL, measured in a running
+// container of the database pod mounting the volume. It returns nil if no pod is running.
+func (r *SqliteDatabaseReconciler) measureDatabase(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (*resource.Quantity, error) {
+	pod, err := r.getRunningPod(ctx, sqliteDB)
+	if err != nil || pod == nil {
+		return nil, err
+	}
+
+	container := databaseContainer(pod)
+	if container == "" {
+		return nil, fmt.Errorf("pod %s has no running container mounting the database", pod.Name)
+	}
+
+	path := "/var/lib/sqlite/" + sqliteDB.Spec.Database.Name
+	script := fmt.Sprintf(`for f in %s %s-wal; do [ -f "$f" ] && stat -c %%s "$f"; done; true`,
+		shellJoin([]string{path}), shellJoin([]string{path}))
+	output, err := r.Executor.Exec(ctx, pod.Namespace, pod.Name, container, []string{"sh", "-c", script})
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, field := range strings.Fields(output) {
+		size, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected file size %q", field)
+		}
+		total += size
+	}
+
+	return resource.NewQuantity(total, resource.BinarySI), nil
+}
+
+// getRunningPod returns a running database pod, preferring the writer which is the pod
+// growing the database, or nil if no pod is running
+func (r *SqliteDatabaseReconciler) getRunningPod(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (*corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(sqliteDB.Namespace), databasePodSelector(sqliteDB)); err != nil {
+		return nil, err
+	}
+
+	var pod *corev1.Pod
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase != corev1.PodRunning {
+			continue
+		}
+		if pod == nil || pods.Items[i].Name == sqliteDB.Status.Writer {
+			pod = &pods.Items[i]
+		}
+	}
+	return pod, nil
+}
+
+// containerRunning returns true if the container of the pod is running
+func containerRunning(pod *corev1.Pod, name string) bool {
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if status.Name == name {
+			return status.State.Running != nil
+		}
+	}
+	return false
+}
+
+// databaseContainer returns the name of a running container of the pod mounting the database
+// volume, preferring Litestream whose image has a shell
+func databaseContainer(pod *corev1.Pod) string {
+	running := make(map[string]bool)
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		running[status.Name] = status.State.Running != nil
+	}
+
+	var found string
+	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		if !running[container.Name] {
+			continue
+		}
+		for _, mount := range container.VolumeMounts {
+			if mount.MountPath != "/var/lib/sqlite" {
+				continue
+			}
+			if container.Name == "litestream" {
+				return container.Name
+			}
+			if found == "" {
+				found = container.Name
+			}
+		}
+	}
+
+	return found
+}
+
+// recordEvent emits an event for the SqliteDatabase if a recorder is set
+func (r *SqliteDatabaseReconciler) recordEvent(sqliteDB *databasev1alpha1.SqliteDatabase, eventType, reason, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(sqliteDB, eventType, reason, message)
+	}
+}
+
+// checkExistingClaim returns why the existing claim of the database cannot be used yet, or an
+// empty string if it exists and no other pod mounts it read-write
+func (r *SqliteDatabaseReconciler) checkExistingClaim(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (string, error) {
+	name := sqliteDB.Spec.Database.Storage.ExistingClaim
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: sqliteDB.Namespace}, pvc); err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Sprintf("Existing claim %s not found", name), nil
+		}
+		return "", err
+	}
+
+	// A second writer would corrupt the database
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(sqliteDB.Namespace)); err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		// The application pods the sidecars are injected in are the writers of a database
+		// without workload
+		if workloadKind(sqliteDB) == workloadKindNone && pod.Labels[databaseLabel] == sqliteDB.Name &&
+			pod.Annotations[InjectedAnnotation] == "true" {
+			continue
+		}
+		writer := false
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == name &&
+				!volume.PersistentVolumeClaim.ReadOnly {
+				writer = true
+			}
+		}
+		if !writer {
+			continue
+		}
+		owned, err := r.ownedByDatabase(ctx, &pod, sqliteDB)
+		if err != nil {
+			return "", err
+		}
+		if !owned {
+			return fmt.Sprintf("Existing claim %s is mounted read-write by pod %s", name, pod.Name), nil
+		}
+	}
+
+	return "", nil
+}
+
+// ownedByDatabase returns true if the object is controlled by the database, directly or
+// through the ReplicaSet of a Deployment, a StatefulSet, a Job or a CronJob
+func (r *SqliteDatabaseReconciler) ownedByDatabase(ctx context.Context, object client.Object, sqliteDB *databasev1alpha1.SqliteDatabase) (bool, error) {
+	// A pod is at most three controllers away from the database
+	for range 3 {
+		owner := metav1.GetControllerOf(object)
+		if owner == nil {
+			return false, nil
+		}
+		if owner.UID == sqliteDB.UID {
+			return true, nil
+		}
+
+		var next client.Object
+		switch owner.APIVersion + "/" + owner.Kind {
+		case "apps/v1/ReplicaSet":
+			next = &appsv1.ReplicaSet{}
+		case "apps/v1/Deployment":
+			next = &appsv1.Deployment{}
+		case "apps/v1/StatefulSet":
+			next = &appsv1.StatefulSet{}
+		case "batch/v1/Job":
+			next = &batchv1.Job{}
+		case "batch/v1/CronJob":
+			next = &batchv1.CronJob{}
+		default:
+			return false, nil
+		}
+		if err := r.Get(ctx, types.NamespacedName{Name: owner.Name, Namespace: object.GetNamespace()}, next); err != nil {
+			if errors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		if next.GetUID() != owner.UID {
+			return false, nil
+		}
+		object = next
+	}
+	return false, nil
+}
+
+// applyStorageSubPath mounts storage.subPath of the database volume instead of its root
+func applyStorageSubPath(sqliteDB *databasev1alpha1.SqliteDatabase, spec *corev1.PodSpec) {
+	subPath := sqliteDB.Spec.Database.Storage.SubPath
+	if subPath == "" {
+		return
+	}
+
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for i := range containers {
+			for j := range containers[i].VolumeMounts {
+				if containers[i].VolumeMounts[j].Name == "db-storage" {
+					containers[i].VolumeMounts[j].SubPath = subPath
+				}
+			}
+		}
+	}
+}
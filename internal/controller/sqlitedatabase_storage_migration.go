@@ -0,0 +1,368 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+const (
+	// storageMigrationAnnotation approves copying the database to a new StorageClass, and
+	// confirms the deletion of the previous claim once the copy is verified
+	storageMigrationAnnotation = "sqlite.io/storage-class-migration"
+
+	storageMigrationApproved  = "approved"
+	storageMigrationConfirmed = "confirmed"
+
+	// databaseClaimAnnotation records the claim holding the database after a StorageClass
+	// migration on the SqliteDatabase, as the status can be lost and <name>-db-storage would
+	// then be mounted again
+	databaseClaimAnnotation = "sqlite.io/database-claim"
+
+	// storageMigratedCondition reports the migration of the database to a new StorageClass
+	storageMigratedCondition = "StorageMigrated"
+)
+
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+
+// storageMigrationJobName returns the name of the Job copying the database to the new claim
+func storageMigrationJobName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	return fmt.Sprintf("%s-storage-migration", sqliteDB.Name)
+}
+
+// reconcileStorageClassMigration copies the database to a new claim when the StorageClass in
+// the spec differs from the one of the current claim, which cannot be changed in place. Once
+// approved with the annotation, the Deployment is stopped, a Job copies the database and checks
+// its integrity on the new claim, and the Deployment is started on it. The previous claim is
+// retained until the annotation is set to confirmed, which the next migration waits for. It
+// returns true while the copy is running.
+func (r *SqliteDatabaseReconciler) reconcileStorageClassMigration(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	if err := r.releasePreviousClaim(ctx, sqliteDB); err != nil {
+		return false, err
+	}
+	// Databases migrated before the claim was annotated
+	if sqliteDB.Status.Storage != nil && sqliteDB.Status.Storage.Claim != "" && sqliteDB.Annotations[databaseClaimAnnotation] == "" {
+		if err := r.annotateDatabaseClaim(ctx, sqliteDB, sqliteDB.Status.Storage.Claim); err != nil {
+			return false, err
+		}
+	}
+
+	if ephemeralStorage(sqliteDB) || sqliteDB.Spec.Database.Storage.StorageClass == nil {
+		return false, nil
+	}
+	storageClass := *sqliteDB.Spec.Database.Storage.StorageClass
+
+	current := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: dbClaimName(sqliteDB), Namespace: sqliteDB.Namespace}, current)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// A new claim is provisioned on the StorageClass
+			return false, nil
+		}
+		return false, err
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      storageMigrationJobName(sqliteDB),
+			Namespace: sqliteDB.Namespace,
+		},
+	}
+	if getStringValue(current.Spec.StorageClassName, "") == storageClass {
+		return false, r.deleteOwned(ctx, sqliteDB, job)
+	}
+
+	condition := metav1.Condition{
+		Type:               storageMigratedCondition,
+		Status:             metav1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+	}
+
+	if workloadKind(sqliteDB) != workloadKindDeployment || sqliteDB.Spec.Database.Storage.ExistingClaim != "" {
+		condition.Reason = "Unsupported"
+		condition.Message = fmt.Sprintf("The database can only be migrated to StorageClass %q with the Deployment workload and a claim created by the operator",
+			storageClass)
+		setCondition(sqliteDB, condition)
+		return false, nil
+	}
+	if sqliteDB.Annotations[storageMigrationAnnotation] != storageMigrationApproved {
+		condition.Reason = "ApprovalRequired"
+		condition.Message = fmt.Sprintf("Set the %s annotation to %s to copy the database from StorageClass %q to %q",
+			storageMigrationAnnotation, storageMigrationApproved, getStringValue(current.Spec.StorageClassName, ""), storageClass)
+		setCondition(sqliteDB, condition)
+		return false, nil
+	}
+	// The claim retained by the last migration would otherwise be left untracked, or reused
+	// as the target when migrating back to its StorageClass
+	if sqliteDB.Status.Storage != nil && sqliteDB.Status.Storage.PreviousClaim != "" {
+		condition.Reason = "PreviousClaimRetained"
+		condition.Message = fmt.Sprintf("Set the %s annotation to %s to delete claim %s, then to %s to copy the database to StorageClass %q",
+			storageMigrationAnnotation, storageMigrationConfirmed, sqliteDB.Status.Storage.PreviousClaim, storageMigrationApproved, storageClass)
+		setCondition(sqliteDB, condition)
+		return false, nil
+	}
+
+	err = r.Get(ctx, client.ObjectKeyFromObject(job), job)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	if err == nil && job.Status.Failed > 0 {
+		// The Deployment keeps running on the current claim until the Job is deleted to retry
+		condition.Reason = "CopyFailed"
+		condition.Message = fmt.Sprintf("Copy or integrity check failed, see Job %s and delete it to retry", job.Name)
+		setCondition(sqliteDB, condition)
+		return false, nil
+	}
+
+	// The writer must be stopped for a consistent copy
+	if err := r.deleteOwned(ctx, sqliteDB, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name:      sqliteDB.Name,
+		Namespace: sqliteDB.Namespace,
+	}}); err != nil {
+		return false, err
+	}
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(sqliteDB.Namespace), client.MatchingLabels{
+		"app.kubernetes.io/name":     "sqlite-database",
+		"app.kubernetes.io/instance": sqliteDB.Name,
+	}); err != nil {
+		return false, err
+	}
+	condition.Reason = "Copying"
+	if len(pods.Items) > 0 {
+		condition.Message = "Waiting for the database pod to stop"
+		setCondition(sqliteDB, condition)
+		return true, nil
+	}
+
+	target, err := r.reconcileMigrationClaim(ctx, sqliteDB, current)
+	if err != nil {
+		return false, err
+	}
+	if target.DeletionTimestamp != nil {
+		condition.Message = fmt.Sprintf("Waiting for the deletion of claim %s", target.Name)
+		setCondition(sqliteDB, condition)
+		return true, nil
+	}
+
+	if job.UID == "" {
+		job = r.buildStorageMigrationJob(sqliteDB, current.Name, target.Name)
+		if err := controllerutil.SetControllerReference(sqliteDB, job, r.Scheme); err != nil {
+			return false, err
+		}
+		log.Info("Copying database to new StorageClass", "from", current.Name, "to", target.Name, "storageClass", storageClass)
+		if err := r.Create(ctx, job); err != nil {
+			return false, err
+		}
+	}
+	if job.Status.Succeeded == 0 {
+		condition.Message = fmt.Sprintf("Copying the database to claim %s on StorageClass %q", target.Name, storageClass)
+		setCondition(sqliteDB, condition)
+		return true, nil
+	}
+
+	// Start the Deployment on the new claim, keeping the previous one until confirmed
+	if err := r.annotateDatabaseClaim(ctx, sqliteDB, target.Name); err != nil {
+		return false, err
+	}
+	if sqliteDB.Status.Storage == nil {
+		sqliteDB.Status.Storage = &databasev1alpha1.StorageStatus{}
+	}
+	sqliteDB.Status.Storage.Claim = target.Name
+	sqliteDB.Status.Storage.PreviousClaim = current.Name
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = "Migrated"
+	condition.Message = fmt.Sprintf("Database copied to StorageClass %q and verified, set the %s annotation to %s to delete claim %s",
+		storageClass, storageMigrationAnnotation, storageMigrationConfirmed, current.Name)
+	setCondition(sqliteDB, condition)
+	if err := r.writeStatus(ctx, sqliteDB); err != nil {
+		return false, err
+	}
+	log.Info("Database migrated to new StorageClass", "claim", target.Name, "previousClaim", current.Name)
+
+	return false, r.deleteOwned(ctx, sqliteDB, job)
+}
+
+// annotateDatabaseClaim records the claim holding the database in its annotation
+func (r *SqliteDatabaseReconciler) annotateDatabaseClaim(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase, claim string) error {
+	// Patch a copy, the response would overwrite the status recorded so far
+	patched := sqliteDB.DeepCopy()
+	if patched.Annotations == nil {
+		patched.Annotations = make(map[string]string)
+	}
+	patched.Annotations[databaseClaimAnnotation] = claim
+	if err := r.Patch(ctx, patched, client.MergeFrom(sqliteDB)); err != nil {
+		return err
+	}
+	sqliteDB.Annotations = patched.Annotations
+	sqliteDB.ResourceVersion = patched.ResourceVersion
+	return nil
+}
+
+// reconcileMigrationClaim creates the claim on the new StorageClass, at least as large as the current one
+func (r *SqliteDatabaseReconciler) reconcileMigrationClaim(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase, current *corev1.PersistentVolumeClaim) (*corev1.PersistentVolumeClaim, error) {
+	target := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-db-storage-%s", sqliteDB.Name, *sqliteDB.Spec.Database.Storage.StorageClass),
+			Namespace: sqliteDB.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "sqlite-database",
+				"app.kubernetes.io/instance":   sqliteDB.Name,
+				"app.kubernetes.io/managed-by": "sqlite-operator",
+			},
+		},
+		Spec: r.buildPVCSpec(sqliteDB),
+	}
+	if requested := current.Spec.Resources.Requests[corev1.ResourceStorage]; requested.Cmp(target.Spec.Resources.Requests[corev1.ResourceStorage]) > 0 {
+		target.Spec.Resources.Requests[corev1.ResourceStorage] = requested
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, target, func() error {
+		return controllerutil.SetControllerReference(sqliteDB, target, r.Scheme)
+	})
+
+	return target, err
+}
+
+// buildStorageMigrationJob builds the Job copying the database and its WAL between two claims
+// and running PRAGMA integrity_check on the copy
+func (r *SqliteDatabaseReconciler) buildStorageMigrationJob(sqliteDB *databasev1alpha1.SqliteDatabase, from, to string) *batchv1.Job {
+	name := sqliteDB.Spec.Database.Name
+	check := fmt.Sprintf(`sqlite3 /target/%s "PRAGMA integrity_check;"`, name)
+	container := corev1.Container{
+		Name:            "copy-db",
+		Image:           r.resolveImages(sqliteDB).Sqlite,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"/bin/sh", "-c"},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "source",
+				MountPath: "/source",
+				SubPath:   sqliteDB.Spec.Database.Storage.SubPath,
+				ReadOnly:  true,
+			},
+			{
+				Name:      "target",
+				MountPath: "/target",
+				SubPath:   sqliteDB.Spec.Database.Storage.SubPath,
+			},
+		},
+	}
+	if sqliteDB.Spec.Database.Encryption != nil {
+		container.Env = append(container.Env, r.buildEncryptionKeyEnv(sqliteDB))
+		check = fmt.Sprintf(`KEY_SQL="PRAGMA key = '$(printf '%%s' "$%s" | sed "s/'/''/g")';"
+printf '%%s\nPRAGMA integrity_check;\n' "$KEY_SQL" | sqlcipher /target/%s`, encryptionKeyEnv, name)
+	}
+	container.Args = []string{fmt.Sprintf(`set -e
+rm -f /target/%[1]s /target/%[1]s-wal /target/%[1]s-shm
+cp -p /source/%[1]s /target/%[1]s
+if [ -f /source/%[1]s-wal ]; then
+  cp -p /source/%[1]s-wal /target/%[1]s-wal
+fi
+result=$(%[2]s)
+echo "$result"
+if [ -z "$result" ] || echo "$result" | grep -qv '^ok$'; then
+  echo "Integrity check of the copied database failed" >&2
+  exit 1
+fi
+echo "Database copied and verified"`, name, check)}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      storageMigrationJobName(sqliteDB),
+			Namespace: sqliteDB.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "sqlite-database",
+				"app.kubernetes.io/instance":   sqliteDB.Name,
+				"app.kubernetes.io/managed-by": "sqlite-operator",
+				"app.kubernetes.io/component":  "storage-migration",
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: ptr.To(int32(0)),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					Containers:       []corev1.Container{container},
+					ImagePullSecrets: sqliteDB.Spec.ImagePullSecrets,
+					Volumes: []corev1.Volume{
+						{
+							Name: "source",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: from,
+									ReadOnly:  true,
+								},
+							},
+						},
+						{
+							Name: "target",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: to,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// releasePreviousClaim deletes the claim retained by the last StorageClass migration once confirmed
+func (r *SqliteDatabaseReconciler) releasePreviousClaim(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	if sqliteDB.Status.Storage == nil || sqliteDB.Status.Storage.PreviousClaim == "" ||
+		sqliteDB.Annotations[storageMigrationAnnotation] != storageMigrationConfirmed {
+		return nil
+	}
+
+	logf.FromContext(ctx).Info("Deleting database claim retained by the StorageClass migration",
+		"claim", sqliteDB.Status.Storage.PreviousClaim)
+	if err := r.deleteOwned(ctx, sqliteDB, &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:      sqliteDB.Status.Storage.PreviousClaim,
+		Namespace: sqliteDB.Namespace,
+	}}); err != nil {
+		return err
+	}
+	sqliteDB.Status.Storage.PreviousClaim = ""
+
+	setCondition(sqliteDB, metav1.Condition{
+		Type:               storageMigratedCondition,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             "Migrated",
+		Message:            fmt.Sprintf("Database migrated to claim %s", dbClaimName(sqliteDB)),
+	})
+	return nil
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+const (
+	// backupVerifiedCondition reports the result of the last verification of a replica
+	backupVerifiedCondition = "BackupVerified"
+
+	// backupVerificationInterval is the interval between checks of the verification Jobs
+	backupVerificationInterval = 5 * time.Minute
+)
+
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+
+// backupVerificationEnabled returns true if a replica of the database is verified periodically
+func backupVerificationEnabled(sqliteDB *databasev1alpha1.SqliteDatabase) bool {
+	litestream := sqliteDB.Spec.Litestream
+	return litestreamReplicating(sqliteDB) && litestream.Verification != nil
+}
+
+// backupVerificationName returns the name of the CronJob and ConfigMap verifying the replica
+func backupVerificationName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	return fmt.Sprintf("%s-backup-verification", sqliteDB.Name)
+}
+
+// verifiedReplicaDatabase returns a copy of the database replicating to the verified replica only
+func verifiedReplicaDatabase(sqliteDB *databasev1alpha1.SqliteDatabase) *databasev1alpha1.SqliteDatabase {
+	litestream := sqliteDB.Spec.Litestream
+	return replicaSourceDatabase(sqliteDB, &databasev1alpha1.ReplicaDataSource{
+		Replicas: []databasev1alpha1.ReplicaConfig{litestream.Replicas[litestream.Verification.Replica]},
+		Database: sqliteDB.Spec.Database.Name,
+	})
+}
+
+// reconcileBackupVerification creates or updates the CronJob verifying the replica, and sets
+// the BackupVerified condition from the last finished verification Job
+func (r *SqliteDatabaseReconciler) reconcileBackupVerification(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	objectMeta := metav1.ObjectMeta{
+		Name:      backupVerificationName(sqliteDB),
+		Namespace: sqliteDB.Namespace,
+	}
+	cronJob := &batchv1.CronJob{ObjectMeta: objectMeta}
+	configMap := &corev1.ConfigMap{ObjectMeta: objectMeta}
+
+	if !backupVerificationEnabled(sqliteDB) {
+		meta.RemoveStatusCondition(&sqliteDB.Status.Conditions, backupVerifiedCondition)
+		sqliteDB.Status.LastVerified = nil
+		if err := r.deleteOwned(ctx, sqliteDB, cronJob); err != nil {
+			return err
+		}
+		return r.deleteOwned(ctx, sqliteDB, configMap)
+	}
+
+	labels := map[string]string{
+		"app.kubernetes.io/name":       "sqlite-database",
+		"app.kubernetes.io/instance":   sqliteDB.Name,
+		"app.kubernetes.io/managed-by": "sqlite-operator",
+		"app.kubernetes.io/component":  "backup-verification",
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		configMap.Labels = labels
+		configMap.Data = map[string]string{
+			"litestream.yml": r.buildLitestreamConfig(verifiedReplicaDatabase(sqliteDB)),
+		}
+		return controllerutil.SetControllerReference(sqliteDB, configMap, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, cronJob, func() error {
+		cronJob.Labels = labels
+		cronJob.Spec.Schedule = sqliteDB.Spec.Litestream.Verification.Schedule
+		cronJob.Spec.ConcurrencyPolicy = batchv1.ForbidConcurrent
+		cronJob.Spec.SuccessfulJobsHistoryLimit = ptr.To(int32(1))
+		cronJob.Spec.FailedJobsHistoryLimit = ptr.To(int32(1))
+		cronJob.Spec.JobTemplate = batchv1.JobTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: labels,
+			},
+			Spec: batchv1.JobSpec{
+				BackoffLimit: ptr.To(int32(0)),
+				Template: corev1.PodTemplateSpec{
+					Spec: r.buildBackupVerificationPodSpec(sqliteDB),
+				},
+			},
+		}
+		return controllerutil.SetControllerReference(sqliteDB, cronJob, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	return r.updateBackupVerifiedCondition(ctx, sqliteDB, labels)
+}
+
+// buildBackupVerificationPodSpec builds the pod restoring the replica into an emptyDir and
+// checking the restored database. A failure is reported in the termination message of the
+// failed container.
+func (r *SqliteDatabaseReconciler) buildBackupVerificationPodSpec(sqliteDB *databasev1alpha1.SqliteDatabase) corev1.PodSpec {
+	images := r.resolveImages(sqliteDB)
+	verification := sqliteDB.Spec.Litestream.Verification
+	dbPath := fmt.Sprintf("/verify/%s", sqliteDB.Spec.Database.Name)
+
+	restoreContainer := corev1.Container{
+		Name:            "restore-replica",
+		Image:           images.Litestream,
+		ImagePullPolicy: sqliteDB.Spec.ImagePullPolicy,
+		Command:         []string{"litestream"},
+		Args: []string{
+			"restore", "-config", "/etc/litestream/litestream.yml",
+			"-o", dbPath,
+			fmt.Sprintf("/var/lib/sqlite/%s", sqliteDB.Spec.Database.Name),
+		},
+		Env:                      r.buildLitestreamEnv(verifiedReplicaDatabase(sqliteDB)),
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "verify",
+				MountPath: "/verify",
+			},
+			{
+				Name:      "litestream-config",
+				MountPath: "/etc/litestream",
+			},
+		},
+	}
+
+	verifyContainer := corev1.Container{
+		Name:                     "verify-backup",
+		Image:                    images.Sqlite,
+		ImagePullPolicy:          sqliteDB.Spec.ImagePullPolicy,
+		Command:                  []string{"/bin/sh", "-c"},
+		Args:                     []string{buildBackupVerificationScript(sqliteDB, dbPath)},
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "verify",
+				MountPath: "/verify",
+			},
+		},
+	}
+	if verification.Resources != nil {
+		restoreContainer.Resources = *verification.Resources
+		verifyContainer.Resources = *verification.Resources
+	}
+
+	// The restored database is never larger than the database volume
+	sizeLimit := resource.MustParse(sqliteDB.Spec.Database.Storage.Size)
+
+	return corev1.PodSpec{
+		RestartPolicy:    corev1.RestartPolicyNever,
+		InitContainers:   []corev1.Container{restoreContainer},
+		Containers:       []corev1.Container{verifyContainer},
+		ImagePullSecrets: sqliteDB.Spec.ImagePullSecrets,
+		Volumes: []corev1.Volume{
+			{
+				Name: "verify",
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{
+						SizeLimit: &sizeLimit,
+					},
+				},
+			},
+			{
+				Name: "litestream-config",
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: backupVerificationName(sqliteDB),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// buildQueryFunction builds the query shell function printing the result of the SQL in its
+// argument on the database, after waiting up to busyTimeout milliseconds for its locks
+func buildQueryFunction(sqliteDB *databasev1alpha1.SqliteDatabase, dbPath string, busyTimeout int32) string {
+	if sqliteDB.Spec.Database.Encryption != nil {
+		// The output of PRAGMA key is discarded so that only the result of the query remains
+		return fmt.Sprintf(`KEY_SQL="PRAGMA key = '$(printf '%%s' "$%s" | sed "s/'/''/g")';"
+query() {
+  printf '.output /dev/null\n%%s\n.timeout %d\n.output stdout\n%%s\n' "$KEY_SQL" "$1" | sqlcipher -bail %s 2>&1
+}`, encryptionKeyEnv, busyTimeout, dbPath)
+	}
+
+	return fmt.Sprintf(`query() {
+  sqlite3 -bail -cmd '.timeout %d' %s "$1" 2>&1
+}`, busyTimeout, dbPath)
+}
+
+// buildBackupVerificationScript builds the script checking the integrity of the restored
+// database and its assertions
+func buildBackupVerificationScript(sqliteDB *databasev1alpha1.SqliteDatabase, dbPath string) string {
+	query := buildQueryFunction(sqliteDB, dbPath, 0)
+
+	var script strings.Builder
+	fmt.Fprintf(&script, `set -u
+%s
+fail() {
+  echo "$1" >&2
+  exit 1
+}
+result=$(query "PRAGMA integrity_check;") || fail "integrity_check failed: $result"
+[ "$result" = ok ] || fail "integrity_check: $(echo "$result" | head -n 5)"
+`, query)
+	for _, assertion := range sqliteDB.Spec.Litestream.Verification.Assertions {
+		name := shellJoin([]string{assertion.Name})
+		fmt.Fprintf(&script, `result=$(query %s) || fail "Assertion "%s" failed: $result"
+[ "$result" = 1 ] || fail "Assertion "%s" returned $result"
+`, shellJoin([]string{assertion.Query}), name, name)
+	}
+	fmt.Fprintf(&script, `echo "Replica verified: integrity ok, %d assertions passed"`,
+		len(sqliteDB.Spec.Litestream.Verification.Assertions))
+
+	return script.String()
+}
+
+// updateBackupVerifiedCondition sets the BackupVerified condition from the most recent
+// finished verification Job
+func (r *SqliteDatabaseReconciler) updateBackupVerifiedCondition(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase, labels map[string]string) error {
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(sqliteDB.Namespace), client.MatchingLabels(labels)); err != nil {
+		return err
+	}
+
+	var last *batchv1.Job
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if job.Status.Succeeded == 0 && job.Status.Failed == 0 {
+			continue
+		}
+		if last == nil || job.CreationTimestamp.After(last.CreationTimestamp.Time) {
+			last = job
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               backupVerifiedCondition,
+		Status:             metav1.ConditionUnknown,
+		LastTransitionTime: metav1.Now(),
+		Reason:             "Pending",
+		Message:            fmt.Sprintf("Waiting for the first verification on schedule %q", sqliteDB.Spec.Litestream.Verification.Schedule),
+	}
+
+	switch {
+	case last == nil:
+	case last.Status.Succeeded > 0:
+		verified := last.CreationTimestamp
+		if last.Status.CompletionTime != nil {
+			verified = *last.Status.CompletionTime
+		}
+		sqliteDB.Status.LastVerified = &verified
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Verified"
+		condition.Message = fmt.Sprintf("Replica restored and verified by Job %s", last.Name)
+	default:
+		message, err := r.getJobFailureMessage(ctx, last)
+		if err != nil {
+			return err
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "VerificationFailed"
+		condition.Message = fmt.Sprintf("Verification Job %s failed: %s", last.Name, message)
+	}
+	setCondition(sqliteDB, condition)
+
+	return nil
+}
+
+// getJobFailureMessage returns the termination message of the failed container of the Job
+func (r *SqliteDatabaseReconciler) getJobFailureMessage(ctx context.Context, job *batchv1.Job) (string, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{
+		batchv1.JobNameLabel: job.Name,
+	}); err != nil {
+		return "", err
+	}
+
+	for _, pod := range pods.Items {
+		statuses := append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+				if message := strings.TrimSpace(terminated.Message); message != "" {
+					return fmt.Sprintf("%s: %s", status.Name, message), nil
+				}
+				return fmt.Sprintf("%s exited with code %d", status.Name, terminated.ExitCode), nil
+			}
+		}
+	}
+
+	return "see the logs of the Job", nil
+}
@@ -0,0 +1,415 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+)
+
+const (
+	workloadKindDeployment  = "Deployment"
+	workloadKindStatefulSet = "StatefulSet"
+	workloadKindNone        = "None"
+
+	// reclaimPolicyAnnotation records the reclaim policy of a volume while it is re-bound
+	reclaimPolicyAnnotation = "sqlite.io/original-reclaim-policy"
+)
+
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=persistentvolumes,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+
+// workloadKind returns the kind of workload running the database pod. LiteFS always runs
+// its pods in a StatefulSet.
+func workloadKind(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	if liteFSEnabled(sqliteDB) {
+		return workloadKindStatefulSet
+	}
+	if sqliteDB.Spec.Workload == nil || sqliteDB.Spec.Workload.Kind == "" {
+		return workloadKindDeployment
+	}
+	return sqliteDB.Spec.Workload.Kind
+}
+
+// dbClaimName returns the name of the PersistentVolumeClaim holding the database
+func dbClaimName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	if workloadKind(sqliteDB) == workloadKindStatefulSet {
+		return statefulSetClaimName(sqliteDB)
+	}
+	return deploymentClaimName(sqliteDB)
+}
+
+// deploymentClaimName returns the name of the standalone claim used by the Deployment
+func deploymentClaimName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	if sqliteDB.Spec.Database.Storage.ExistingClaim != "" {
+		return sqliteDB.Spec.Database.Storage.ExistingClaim
+	}
+	if claim := sqliteDB.Annotations[databaseClaimAnnotation]; claim != "" {
+		return claim
+	}
+	if sqliteDB.Status.Storage != nil && sqliteDB.Status.Storage.Claim != "" {
+		return sqliteDB.Status.Storage.Claim
+	}
+	return fmt.Sprintf("%s-db-storage", sqliteDB.Name)
+}
+
+// statefulSetClaimName returns the name of the claim created from the volumeClaimTemplate
+func statefulSetClaimName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	return fmt.Sprintf("db-storage-%s-0", sqliteDB.Name)
+}
+
+// headlessServiceName returns the name of the headless Service governing the StatefulSet
+func headlessServiceName(sqliteDB *databasev1alpha1.SqliteDatabase) string {
+	return fmt.Sprintf("%s-headless", sqliteDB.Name)
+}
+
+// reconcileWorkloadMigration removes the workloads of the other kinds and re-binds their
+// volume to the claim used by the current kind. It returns true while the migration is
+// in progress.
+func (r *SqliteDatabaseReconciler) reconcileWorkloadMigration(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (bool, error) {
+	objectMeta := metav1.ObjectMeta{
+		Name:      sqliteDB.Name,
+		Namespace: sqliteDB.Namespace,
+	}
+
+	var previous []client.Object
+	previousClaim := statefulSetClaimName(sqliteDB)
+	switch workloadKind(sqliteDB) {
+	case workloadKindStatefulSet:
+		previous = []client.Object{&appsv1.Deployment{ObjectMeta: objectMeta}}
+		previousClaim = deploymentClaimName(sqliteDB)
+	case workloadKindNone:
+		previous = []client.Object{&appsv1.Deployment{ObjectMeta: objectMeta}, &appsv1.StatefulSet{ObjectMeta: objectMeta}}
+	default:
+		previous = []client.Object{&appsv1.StatefulSet{ObjectMeta: objectMeta}}
+	}
+	if workloadKind(sqliteDB) != workloadKindStatefulSet {
+		if err := r.deleteOwned(ctx, sqliteDB, &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			Name:      headlessServiceName(sqliteDB),
+			Namespace: sqliteDB.Namespace,
+		}}); err != nil {
+			return false, err
+		}
+	}
+
+	// The previous pod must be gone before its claim can be released
+	for _, obj := range previous {
+		if err := r.deleteOwned(ctx, sqliteDB, obj); err != nil {
+			return false, err
+		}
+	}
+
+	// An ephemeral database has no volume to move, it is restored from the replica
+	if ephemeralStorage(sqliteDB) {
+		return false, nil
+	}
+
+	return r.rebindClaim(ctx, sqliteDB, previousClaim, dbClaimName(sqliteDB))
+}
+
+// rebindClaim moves the volume bound to the claim named from to a new claim named to.
+// The volume is retained while the claims are swapped and its reclaim policy is restored
+// once the new claim is bound. It returns true while the re-binding is in progress.
+func (r *SqliteDatabaseReconciler) rebindClaim(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase, from, to string) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	target := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: to, Namespace: sqliteDB.Namespace}, target)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+
+	if errors.IsNotFound(err) {
+		source := &corev1.PersistentVolumeClaim{}
+		if err := r.Get(ctx, types.NamespacedName{Name: from, Namespace: sqliteDB.Namespace}, source); err != nil {
+			if errors.IsNotFound(err) {
+				// Nothing to migrate, a new claim will be provisioned
+				return false, nil
+			}
+			return false, err
+		}
+		if !metav1.IsControlledBy(source, sqliteDB) {
+			return false, nil
+		}
+		if source.Spec.VolumeName == "" {
+			// An unbound claim holds no data, the new claim is provisioned from scratch
+			log.Info("Deleting unbound database claim", "claim", from)
+			if err := r.Delete(ctx, source); err != nil && !errors.IsNotFound(err) {
+				return false, err
+			}
+			return false, nil
+		}
+
+		// Retain the volume when the previous claim is deleted
+		volume := &corev1.PersistentVolume{}
+		if err := r.Get(ctx, types.NamespacedName{Name: source.Spec.VolumeName}, volume); err != nil {
+			return false, err
+		}
+		if volume.Spec.PersistentVolumeReclaimPolicy != corev1.PersistentVolumeReclaimRetain {
+			if volume.Annotations == nil {
+				volume.Annotations = make(map[string]string)
+			}
+			volume.Annotations[reclaimPolicyAnnotation] = string(volume.Spec.PersistentVolumeReclaimPolicy)
+			volume.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimRetain
+			if err := r.Update(ctx, volume); err != nil {
+				return false, err
+			}
+		}
+
+		// Create the new claim pre-bound to the volume, it stays pending until the volume is released
+		target = &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      to,
+				Namespace: sqliteDB.Namespace,
+				Labels:    source.Labels,
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      source.Spec.AccessModes,
+				Resources:        source.Spec.Resources,
+				StorageClassName: source.Spec.StorageClassName,
+				VolumeMode:       source.Spec.VolumeMode,
+				VolumeName:       volume.Name,
+			},
+		}
+		if err := controllerutil.SetControllerReference(sqliteDB, target, r.Scheme); err != nil {
+			return false, err
+		}
+
+		log.Info("Re-binding database volume", "volume", volume.Name, "from", from, "to", to)
+		return true, r.Create(ctx, target)
+	}
+
+	if target.Status.Phase == corev1.ClaimBound {
+		return false, r.restoreReclaimPolicy(ctx, target.Spec.VolumeName)
+	}
+	if target.Spec.VolumeName == "" {
+		// A new claim waiting to be provisioned, not a migration
+		return false, nil
+	}
+
+	// Delete the previous claim, which completes once no pod uses it anymore
+	source := &corev1.PersistentVolumeClaim{}
+	err = r.Get(ctx, types.NamespacedName{Name: from, Namespace: sqliteDB.Namespace}, source)
+	if err == nil {
+		if source.DeletionTimestamp == nil {
+			if err := r.Delete(ctx, source); err != nil && !errors.IsNotFound(err) {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	if !errors.IsNotFound(err) {
+		return false, err
+	}
+
+	// Point the released volume at the new claim
+	volume := &corev1.PersistentVolume{}
+	if err := r.Get(ctx, types.NamespacedName{Name: target.Spec.VolumeName}, volume); err != nil {
+		return false, err
+	}
+	if volume.Spec.ClaimRef == nil || volume.Spec.ClaimRef.Name != to || volume.Spec.ClaimRef.Namespace != sqliteDB.Namespace {
+		volume.Spec.ClaimRef = &corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "PersistentVolumeClaim",
+			Namespace:  sqliteDB.Namespace,
+			Name:       to,
+		}
+		if err := r.Update(ctx, volume); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// restoreReclaimPolicy restores the reclaim policy of a volume after it has been re-bound
+func (r *SqliteDatabaseReconciler) restoreReclaimPolicy(ctx context.Context, volumeName string) error {
+	volume := &corev1.PersistentVolume{}
+	if err := r.Get(ctx, types.NamespacedName{Name: volumeName}, volume); err != nil {
+		return err
+	}
+
+	policy, ok := volume.Annotations[reclaimPolicyAnnotation]
+	if !ok {
+		return nil
+	}
+	volume.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimPolicy(policy)
+	delete(volume.Annotations, reclaimPolicyAnnotation)
+
+	return r.Update(ctx, volume)
+}
+
+// deleteOwned deletes an object if it exists and is controlled by the SqliteDatabase
+func (r *SqliteDatabaseReconciler) deleteOwned(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase, obj client.Object) error {
+	if err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !metav1.IsControlledBy(obj, sqliteDB) || obj.GetDeletionTimestamp() != nil {
+		return nil
+	}
+
+	if err := r.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// reconcileStatefulSet creates or updates the StatefulSet
+func (r *SqliteDatabaseReconciler) reconcileStatefulSet(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sqliteDB.Name,
+			Namespace: sqliteDB.Namespace,
+		},
+	}
+
+	// The volumeClaimTemplates are immutable, so the StatefulSet is recreated when the storage type changes
+	if err := r.Get(ctx, client.ObjectKeyFromObject(statefulSet), statefulSet); err == nil {
+		if metav1.IsControlledBy(statefulSet, sqliteDB) && (len(statefulSet.Spec.VolumeClaimTemplates) > 0) == ephemeralStorage(sqliteDB) {
+			if err := r.Delete(ctx, statefulSet); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+			return fmt.Errorf("recreating StatefulSet %s for the new storage type", statefulSet.Name)
+		}
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, statefulSet, func() error {
+		statefulSet.Labels = map[string]string{
+			"app.kubernetes.io/name":       "sqlite-database",
+			"app.kubernetes.io/instance":   sqliteDB.Name,
+			"app.kubernetes.io/managed-by": "sqlite-operator",
+		}
+		statefulSet.Spec.Replicas = int32Ptr(1)
+		statefulSet.Spec.Template = r.buildPodTemplate(sqliteDB)
+
+		// The selector, service name and claim templates are immutable
+		if statefulSet.CreationTimestamp.IsZero() {
+			statefulSet.Spec.Selector = &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/name":     "sqlite-database",
+					"app.kubernetes.io/instance": sqliteDB.Name,
+				},
+			}
+			statefulSet.Spec.ServiceName = headlessServiceName(sqliteDB)
+			statefulSet.Spec.PodManagementPolicy = appsv1.OrderedReadyPodManagement
+		}
+		if statefulSet.CreationTimestamp.IsZero() && !ephemeralStorage(sqliteDB) {
+			statefulSet.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "db-storage",
+						Labels: map[string]string{
+							"app.kubernetes.io/name":       "sqlite-database",
+							"app.kubernetes.io/instance":   sqliteDB.Name,
+							"app.kubernetes.io/managed-by": "sqlite-operator",
+						},
+					},
+					Spec: r.buildPVCSpec(sqliteDB),
+				},
+			}
+		}
+
+		return controllerutil.SetControllerReference(sqliteDB, statefulSet, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The claim outlives the StatefulSet so that it can be re-bound when switching kinds,
+	// and is deleted with the SqliteDatabase like the Deployment claim
+	claim := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, types.NamespacedName{Name: statefulSetClaimName(sqliteDB), Namespace: sqliteDB.Namespace}, claim); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if metav1.GetControllerOf(claim) == nil {
+		if err := controllerutil.SetControllerReference(sqliteDB, claim, r.Scheme); err != nil {
+			return err
+		}
+		return r.Update(ctx, claim)
+	}
+
+	return nil
+}
+
+// reconcileHeadlessService creates or updates the headless Service giving the StatefulSet pod a stable identity
+func (r *SqliteDatabaseReconciler) reconcileHeadlessService(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) error {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      headlessServiceName(sqliteDB),
+			Namespace: sqliteDB.Namespace,
+		},
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, service, func() error {
+		service.Labels = map[string]string{
+			"app.kubernetes.io/name":       "sqlite-database",
+			"app.kubernetes.io/instance":   sqliteDB.Name,
+			"app.kubernetes.io/managed-by": "sqlite-operator",
+		}
+		service.Spec.ClusterIP = corev1.ClusterIPNone
+		service.Spec.PublishNotReadyAddresses = true
+		service.Spec.Selector = map[string]string{
+			"app.kubernetes.io/name":     "sqlite-database",
+			"app.kubernetes.io/instance": sqliteDB.Name,
+		}
+		return controllerutil.SetControllerReference(sqliteDB, service, r.Scheme)
+	})
+
+	return err
+}
+
+// getReadyReplicas returns the number of ready pods of the workload
+func (r *SqliteDatabaseReconciler) getReadyReplicas(ctx context.Context, sqliteDB *databasev1alpha1.SqliteDatabase) (int32, error) {
+	key := types.NamespacedName{
+		Name:      sqliteDB.Name,
+		Namespace: sqliteDB.Namespace,
+	}
+
+	switch workloadKind(sqliteDB) {
+	case workloadKindNone:
+		return r.getReadyInjectedPods(ctx, sqliteDB)
+	case workloadKindStatefulSet:
+		statefulSet := &appsv1.StatefulSet{}
+		if err := r.Get(ctx, key, statefulSet); err != nil {
+			return 0, err
+		}
+		return statefulSet.Status.ReadyReplicas, nil
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(ctx, key, deployment); err != nil {
+		return 0, err
+	}
+	return deployment.Status.ReadyReplicas, nil
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fetch downloads the data source of a database from an HTTP(S) or S3 URL and
+// verifies its checksum before the file is imported.
+package fetch
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Config configures a download
+type Config struct {
+	// URL of the file, http://, https:// or s3://bucket/key
+	URL string
+	// Output is the path the file is written to
+	Output string
+	// SHA256 is the expected hex encoded checksum of the file
+	SHA256 string
+
+	// Region of the S3 bucket, us-east-1 when empty
+	Region string
+	// Endpoint of an S3-compatible service, AWS when empty
+	Endpoint string
+	// AccessKeyID and SecretAccessKey sign S3 requests, which are anonymous when empty
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Fetch downloads the file to the output path. The file is written to a temporary path and
+// only renamed once its checksum matches, so a partial or tampered download is never imported.
+func Fetch(ctx context.Context, client *http.Client, config Config) error {
+	req, err := newRequest(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", config.URL, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(config.Output), ".fetch-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), resp.Body); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(sum, config.SHA256) {
+		return fmt.Errorf("checksum mismatch for %s: got sha256 %s, want %s", config.URL, sum, config.SHA256)
+	}
+
+	return os.Rename(tmp.Name(), config.Output)
+}
+
+// newRequest builds the GET request for the URL, signed for S3 when credentials are set
+func newRequest(ctx context.Context, config Config) (*http.Request, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return http.NewRequestWithContext(ctx, http.MethodGet, config.URL, nil)
+	case "s3":
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+
+	region := config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if bucket == "" || key == "" {
+		return nil, fmt.Errorf("S3 URL %s must be s3://bucket/key", config.URL)
+	}
+
+	// Path-style requests for S3-compatible services, virtual-hosted style for AWS
+	var objectURL string
+	if config.Endpoint != "" {
+		endpoint := config.Endpoint
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "https://" + endpoint
+		}
+		objectURL = fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(endpoint, "/"), bucket, uriEncodePath(key))
+	} else {
+		objectURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, uriEncodePath(key))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, objectURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if config.AccessKeyID != "" {
+		signV4(req, config.AccessKeyID, config.SecretAccessKey, region, time.Now().UTC())
+	}
+	return req, nil
+}
+
+// signV4 signs an S3 request with AWS Signature Version 4
+func signV4(req *http.Request, accessKeyID, secretAccessKey, region string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, region)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": "UNSIGNED-PAYLOAD",
+		"x-amz-date":           amzDate,
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncodePath encodes each segment of an object key as S3 expects, keeping the slashes
+func uriEncodePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		var encoded strings.Builder
+		for _, b := range []byte(segment) {
+			if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') ||
+				b == '-' || b == '_' || b == '.' || b == '~' {
+				encoded.WriteByte(b)
+				continue
+			}
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+		segments[i] = encoded.String()
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lease implements the writer lease held by a database pod, so that at most
+// one pod writes to the database file at any time, and the election of the LiteFS primary.
+package lease
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// ErrLeaseLost is returned by Hold when the lease could not be renewed.
+var ErrLeaseLost = errors.New("writer lease lost")
+
+// Config configures the writer lease
+type Config struct {
+	// Namespace and Name of the coordination.k8s.io Lease
+	Namespace string
+	Name      string
+
+	// Identity of the holder, the pod name
+	Identity string
+
+	// Timings of the lease, see leaderelection.LeaderElectionConfig
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// Holder acquires and renews the writer lease
+type Holder struct {
+	client  kubernetes.Interface
+	config  Config
+	held    atomic.Bool
+	keep    atomic.Bool
+	elector atomic.Pointer[leaderelection.LeaderElector]
+}
+
+// NewHolder returns a Holder for the lease described by config
+func NewHolder(client kubernetes.Interface, config Config) *Holder {
+	return &Holder{client: client, config: config}
+}
+
+// Hold acquires the lease and keeps renewing it until ctx is done, releasing it on
+// return. It returns ErrLeaseLost if the lease could not be renewed.
+func (h *Holder) Hold(ctx context.Context) error {
+	return h.Elect(ctx, func(bool) {})
+}
+
+// Elect is Hold for a primary election among the pods: onRole is called with true once the
+// lease is acquired and with false when it is lost or released.
+func (h *Holder) Elect(ctx context.Context, onRole func(primary bool)) error {
+	elector, err := h.newElector(leaderelection.LeaderCallbacks{
+		OnStartedLeading: func(context.Context) {
+			h.held.Store(true)
+			onRole(true)
+		},
+		OnStoppedLeading: func() {
+			h.held.Store(false)
+			onRole(false)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	h.elector.Store(elector)
+
+	elector.Run(ctx)
+
+	if ctx.Err() == nil {
+		return ErrLeaseLost
+	}
+	if h.keep.Load() {
+		return nil
+	}
+	// The lease is released once the renewals stopped, as leaderelection does with
+	// ReleaseOnCancel, so the next pod does not wait for it to expire
+	return h.updateLease(func(spec *coordinationv1.LeaseSpec) {
+		now := metav1.NewMicroTime(time.Now())
+		holder := ""
+		duration := int32(1)
+		spec.HolderIdentity = &holder
+		spec.LeaseDurationSeconds = &duration
+		spec.AcquireTime = &now
+		spec.RenewTime = &now
+	})
+}
+
+// Keep makes Elect return without releasing the lease, so that a pod restarting with the
+// same identity takes it over again
+func (h *Holder) Keep() {
+	h.keep.Store(true)
+}
+
+// Extend renews the lease held by this identity for duration. It is used once Elect returned,
+// as the renewals would otherwise shorten it again.
+func (h *Holder) Extend(duration time.Duration) error {
+	return h.updateLease(func(spec *coordinationv1.LeaseSpec) {
+		now := metav1.NewMicroTime(time.Now())
+		seconds := int32(duration.Seconds())
+		spec.LeaseDurationSeconds = &seconds
+		spec.RenewTime = &now
+	})
+}
+
+// Leader returns the holder of the lease last observed by Elect, empty until the first
+// attempt to acquire it
+func (h *Holder) Leader() string {
+	elector := h.elector.Load()
+	if elector == nil {
+		return ""
+	}
+	return elector.GetLeader()
+}
+
+// Held reports whether the lease is currently held
+func (h *Holder) Held() bool {
+	return h.held.Load()
+}
+
+// ServeHTTP reports whether the lease is held, for use as a liveness probe of the
+// writer containers so that they are stopped as soon as the lease is lost.
+func (h *Holder) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	if !h.Held() {
+		http.Error(w, "writer lease not held", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok"))
+}
+
+// updateLease updates the lease if it exists and is held by this identity
+func (h *Holder) updateLease(update func(spec *coordinationv1.LeaseSpec)) error {
+	// Elect returns after its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.RenewDeadline)
+	defer cancel()
+
+	leases := h.client.CoordinationV1().Leases(h.config.Namespace)
+	lease, err := leases.Get(ctx, h.config.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != h.config.Identity {
+		return nil
+	}
+	update(&lease.Spec)
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+func (h *Holder) newElector(callbacks leaderelection.LeaderCallbacks) (*leaderelection.LeaderElector, error) {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Namespace: h.config.Namespace,
+			Name:      h.config.Name,
+		},
+		Client: h.client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: h.config.Identity,
+		},
+	}
+
+	return leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: h.config.LeaseDuration,
+		RenewDeadline: h.config.RenewDeadline,
+		RetryPeriod:   h.config.RetryPeriod,
+		Callbacks:     callbacks,
+		Name:          h.config.Name,
+	})
+}
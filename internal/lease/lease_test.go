@@ -0,0 +1,166 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lease
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testConfig(identity string) Config {
+	return Config{
+		Namespace:     "default",
+		Name:          "db-writer",
+		Identity:      identity,
+		LeaseDuration: 2 * time.Second,
+		RenewDeadline: time.Second,
+		RetryPeriod:   100 * time.Millisecond,
+	}
+}
+
+func TestHoldServesLeaseState(t *testing.T) {
+	client := fake.NewClientset()
+	holder := NewHolder(client, testConfig("pod-a"))
+
+	recorder := httptest.NewRecorder()
+	holder.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status before acquiring = %d, want %d", recorder.Code, http.StatusServiceUnavailable)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- holder.Hold(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !holder.Held() {
+		if time.Now().After(deadline) {
+			t.Fatal("lease was not acquired")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	recorder = httptest.NewRecorder()
+	holder.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status while holding = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Hold() error = %v", err)
+	}
+
+	// The lease is released on cancellation so the next pod does not wait for it to expire
+	lease, err := client.CoordinationV1().Leases("default").Get(context.Background(), "db-writer", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity != "" {
+		t.Fatalf("HolderIdentity = %q after release, want empty", *lease.Spec.HolderIdentity)
+	}
+}
+
+func TestElectHandsOverPrimary(t *testing.T) {
+	client := fake.NewClientset()
+	ctx := context.Background()
+
+	var roleA, roleB atomic.Bool
+	ctxA, cancelA := context.WithCancel(ctx)
+	doneA := make(chan error)
+	go func() { doneA <- NewHolder(client, testConfig("pod-a")).Elect(ctxA, roleA.Store) }()
+	waitFor(t, roleA.Load, "pod-a was not elected")
+
+	ctxB, cancelB := context.WithCancel(ctx)
+	defer cancelB()
+	go func() { _ = NewHolder(client, testConfig("pod-b")).Elect(ctxB, roleB.Store) }()
+	time.Sleep(300 * time.Millisecond)
+	if roleB.Load() {
+		t.Fatal("pod-b elected while pod-a holds the lease")
+	}
+
+	// Releasing the lease on shutdown hands it over without waiting for it to expire
+	cancelA()
+	if err := <-doneA; err != nil {
+		t.Fatalf("Elect() error = %v", err)
+	}
+	if roleA.Load() {
+		t.Fatal("pod-a still primary after shutdown")
+	}
+	waitFor(t, roleB.Load, "pod-b was not elected")
+}
+
+func TestElectKeepsLeaseAcrossRestart(t *testing.T) {
+	client := fake.NewClientset()
+	ctx := context.Background()
+
+	var roleA atomic.Bool
+	holderA := NewHolder(client, testConfig("pod-a"))
+	ctxA, cancelA := context.WithCancel(ctx)
+	doneA := make(chan error)
+	go func() { doneA <- holderA.Elect(ctxA, roleA.Store) }()
+	waitFor(t, roleA.Load, "pod-a was not elected")
+	if leader := holderA.Leader(); leader != "pod-a" {
+		t.Fatalf("Leader() = %q, want pod-a", leader)
+	}
+
+	// pod-a restarts keeping the lease, extended to cover the restart
+	holderA.Keep()
+	cancelA()
+	if err := <-doneA; err != nil {
+		t.Fatalf("Elect() error = %v", err)
+	}
+	if err := holderA.Extend(10 * time.Second); err != nil {
+		t.Fatalf("Extend() error = %v", err)
+	}
+
+	var roleB atomic.Bool
+	ctxB, cancelB := context.WithCancel(ctx)
+	defer cancelB()
+	holderB := NewHolder(client, testConfig("pod-b"))
+	go func() { _ = holderB.Elect(ctxB, roleB.Store) }()
+	waitFor(t, func() bool { return holderB.Leader() == "pod-a" }, "pod-b did not observe pod-a")
+	time.Sleep(2500 * time.Millisecond)
+	if roleB.Load() {
+		t.Fatal("pod-b elected while the extended lease of pod-a is valid")
+	}
+
+	// The restarted pod-a takes the lease over at once
+	var restartedA atomic.Bool
+	ctxRestarted, cancelRestarted := context.WithCancel(ctx)
+	defer cancelRestarted()
+	go func() { _ = NewHolder(client, testConfig("pod-a")).Elect(ctxRestarted, restartedA.Store) }()
+	waitFor(t, restartedA.Load, "restarted pod-a was not elected")
+}
+
+func waitFor(t *testing.T, condition func() bool, message string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal(message)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+	"github.com/sqlite-operator/sqlite-operator/internal/controller"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var podlog = logf.Log.WithName("pod-resource")
+
+// SetupPodWebhookWithManager registers the webhook for Pod in the manager.
+func SetupPodWebhookWithManager(mgr ctrl.Manager, injector *controller.SqliteDatabaseReconciler) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&corev1.Pod{}).
+		WithDefaulter(&PodCustomDefaulter{
+			Client:   mgr.GetClient(),
+			Injector: injector,
+		}).
+		Complete()
+}
+
+// The webhook only receives pods labelled sqlite.io/inject=true, see the objectSelector in
+// config/webhook/selector_patch.yaml.
+// +kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=fail,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod-v1.sqlite.io,admissionReviewVersions=v1
+
+// PodCustomDefaulter injects the SqliteDatabase named by the sqlite.io/database annotation
+// into application pods labelled sqlite.io/inject=true.
+type PodCustomDefaulter struct {
+	Client client.Client
+
+	// Injector renders the database containers the same way as for the operator's own pods
+	Injector *controller.SqliteDatabaseReconciler
+}
+
+var _ webhook.CustomDefaulter = &PodCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind Pod.
+func (d *PodCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return fmt.Errorf("expected a Pod object but got %T", obj)
+	}
+
+	name := pod.Annotations[controller.DatabaseAnnotation]
+	if name == "" {
+		return nil
+	}
+
+	// Pods created by controllers have no namespace set yet
+	namespace := pod.Namespace
+	if namespace == "" {
+		req, err := admission.RequestFromContext(ctx)
+		if err != nil {
+			return err
+		}
+		namespace = req.Namespace
+	}
+
+	sqliteDB := &databasev1alpha1.SqliteDatabase{}
+	if err := d.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, sqliteDB); err != nil {
+		return fmt.Errorf("unable to get SqliteDatabase %s: %w", name, err)
+	}
+
+	podlog.Info("Injecting database", "namespace", namespace, "pod", getPodName(pod), "database", name)
+	return d.Injector.InjectSidecars(sqliteDB, pod)
+}
+
+// getPodName returns the name of the pod, or its generate name if it has none yet
+func getPodName(pod *corev1.Pod) string {
+	if pod.Name != "" {
+		return pod.Name
+	}
+	return pod.GenerateName
+}
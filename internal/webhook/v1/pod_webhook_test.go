@@ -0,0 +1,190 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasev1alpha1 "github.com/sqlite-operator/sqlite-operator/api/v1alpha1"
+	"github.com/sqlite-operator/sqlite-operator/internal/controller"
+)
+
+var _ = Describe("Pod Webhook", func() {
+	var (
+		ctx       context.Context
+		obj       *corev1.Pod
+		defaulter PodCustomDefaulter
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasev1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		sqliteDB := &databasev1alpha1.SqliteDatabase{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "app-db",
+				Namespace: "default",
+			},
+			Spec: databasev1alpha1.SqliteDatabaseSpec{
+				Database: databasev1alpha1.DatabaseConfig{
+					Name: "app.db",
+					Storage: databasev1alpha1.StorageConfig{
+						Size: "1Gi",
+					},
+				},
+				Litestream: &databasev1alpha1.LitestreamConfig{
+					Enabled: true,
+					Replicas: []databasev1alpha1.ReplicaConfig{
+						{Type: "s3", Bucket: "backups"},
+					},
+				},
+				Workload: &databasev1alpha1.WorkloadConfig{Kind: "None"},
+			},
+		}
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sqliteDB).Build()
+
+		defaulter = PodCustomDefaulter{
+			Client:   fakeClient,
+			Injector: &controller.SqliteDatabaseReconciler{Client: fakeClient, Scheme: scheme},
+		}
+		obj = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "app",
+				Namespace: "default",
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "my-app:latest"}},
+			},
+		}
+	})
+
+	Context("When creating a Pod under Defaulting Webhook", func() {
+		It("Should leave pods without the annotation untouched", func() {
+			expected := obj.DeepCopy()
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj).To(Equal(expected))
+		})
+
+		It("Should inject the database into annotated pods", func() {
+			obj.Annotations = map[string]string{controller.DatabaseAnnotation: "app-db"}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+
+			var initContainers []string
+			for _, container := range obj.Spec.InitContainers {
+				initContainers = append(initContainers, container.Name)
+			}
+			Expect(initContainers).To(Equal([]string{"restore-db", "init-db"}))
+
+			var containers []string
+			for _, container := range obj.Spec.Containers {
+				containers = append(containers, container.Name)
+			}
+			Expect(containers).To(Equal([]string{"app", "litestream"}))
+
+			app := obj.Spec.Containers[0]
+			Expect(app.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "db-storage", MountPath: "/var/lib/sqlite"}))
+			Expect(app.Env).To(ContainElement(corev1.EnvVar{Name: "SQLITE_DATABASE_PATH", Value: "/var/lib/sqlite/app.db"}))
+			Expect(obj.Spec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("app-db-db-storage"))
+			Expect(obj.Spec.ServiceAccountName).To(BeEmpty())
+			Expect(obj.Labels).To(HaveKeyWithValue("sqlite.io/database", "app-db"))
+
+			By("not injecting twice")
+			injected := obj.DeepCopy()
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj).To(Equal(injected))
+		})
+
+		It("Should run the application with Litestream in exec mode", func() {
+			sqliteDB := &databasev1alpha1.SqliteDatabase{}
+			Expect(defaulter.Client.Get(ctx, types.NamespacedName{Name: "app-db", Namespace: "default"}, sqliteDB)).To(Succeed())
+			sqliteDB.Spec.Litestream.Mode = "exec"
+			Expect(defaulter.Client.Update(ctx, sqliteDB)).To(Succeed())
+
+			obj.Annotations = map[string]string{controller.DatabaseAnnotation: "app-db"}
+			obj.Spec.Containers[0].Command = []string{"/app/server"}
+			obj.Spec.Containers[0].Args = []string{"--greeting", "it's ready"}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+
+			var containers []string
+			for _, container := range obj.Spec.Containers {
+				containers = append(containers, container.Name)
+			}
+			Expect(containers).To(Equal([]string{"app"}))
+			Expect(obj.Spec.InitContainers[len(obj.Spec.InitContainers)-1].Name).To(Equal("install-litestream"))
+
+			app := obj.Spec.Containers[0]
+			Expect(app.Command).To(Equal([]string{"/litestream/litestream"}))
+			Expect(app.Args).To(Equal([]string{
+				"replicate", "-config", "/etc/litestream/litestream.yml",
+				"-exec", `/app/server --greeting 'it'\''s ready'`,
+			}))
+		})
+
+		It("Should reject exec mode without a command", func() {
+			sqliteDB := &databasev1alpha1.SqliteDatabase{}
+			Expect(defaulter.Client.Get(ctx, types.NamespacedName{Name: "app-db", Namespace: "default"}, sqliteDB)).To(Succeed())
+			sqliteDB.Spec.Litestream.Mode = "exec"
+			Expect(defaulter.Client.Update(ctx, sqliteDB)).To(Succeed())
+
+			obj.Annotations = map[string]string{controller.DatabaseAnnotation: "app-db"}
+			Expect(defaulter.Default(ctx, obj)).NotTo(Succeed())
+		})
+
+		It("Should reject databases running their own writer", func() {
+			sqliteDB := &databasev1alpha1.SqliteDatabase{}
+			Expect(defaulter.Client.Get(ctx, types.NamespacedName{Name: "app-db", Namespace: "default"}, sqliteDB)).To(Succeed())
+			sqliteDB.Spec.Workload.Kind = "Deployment"
+			Expect(defaulter.Client.Update(ctx, sqliteDB)).To(Succeed())
+
+			obj.Annotations = map[string]string{controller.DatabaseAnnotation: "app-db"}
+			Expect(defaulter.Default(ctx, obj)).To(MatchError(ContainSubstring("runs its own Deployment writer")))
+			Expect(obj.Spec.Containers).To(HaveLen(1))
+		})
+
+		It("Should hold the writer lease in a native sidecar", func() {
+			sqliteDB := &databasev1alpha1.SqliteDatabase{}
+			Expect(defaulter.Client.Get(ctx, types.NamespacedName{Name: "app-db", Namespace: "default"}, sqliteDB)).To(Succeed())
+			sqliteDB.Spec.WriterLease = &databasev1alpha1.WriterLeaseConfig{Enabled: true}
+			Expect(defaulter.Client.Update(ctx, sqliteDB)).To(Succeed())
+
+			obj.Annotations = map[string]string{controller.DatabaseAnnotation: "app-db"}
+			Expect(defaulter.Default(ctx, obj)).To(MatchError(ContainSubstring("native sidecar")))
+
+			defaulter.Injector.NativeSidecars = true
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.InitContainers[0].Name).To(Equal("writer-lease"))
+			Expect(obj.Spec.ServiceAccountName).To(Equal("app-db-sqlite"))
+		})
+
+		It("Should reject pods referencing a missing database", func() {
+			obj.Annotations = map[string]string{controller.DatabaseAnnotation: "missing"}
+			Expect(defaulter.Default(ctx, obj)).NotTo(Succeed())
+		})
+	})
+})